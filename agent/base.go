@@ -10,6 +10,7 @@ import (
 	"github.com/antgroup/aievo/callback"
 	"github.com/antgroup/aievo/feedback"
 	"github.com/antgroup/aievo/llm"
+	"github.com/antgroup/aievo/memory/longterm"
 	"github.com/antgroup/aievo/prompt"
 	"github.com/antgroup/aievo/schema"
 	"github.com/antgroup/aievo/tool"
@@ -32,11 +33,15 @@ type BaseAgent struct {
 	fdChain  feedback.Feedback
 	callback callback.Handler
 	prompt   prompt.Template
+	longTerm *longterm.Memory
+	msgTypes []schema.MsgType
 
-	filterMemoryFunc func([]schema.Message) []schema.Message
-	parseOutputFunc  func(string, *llm.Generation) ([]schema.StepAction, []schema.Message, error)
+	filterMemoryFunc        func([]schema.Message) []schema.Message
+	filterMemoryContextFunc func(context.Context, []schema.Message) []schema.Message
+	parseOutputFunc         func(string, *llm.Generation) ([]schema.StepAction, []schema.Message, error)
 
 	MaxIterations int
+	toolTimeout   time.Duration
 	vars          map[string]string
 }
 
@@ -76,12 +81,16 @@ func NewBaseAgent(opts ...Option) (*BaseAgent, error) {
 		env:             options.Env,
 		tools:           options.Tools,
 		useFunctionCall: options.useFunctionCall,
-		fdChain:         options.FeedbackChain,
+		fdChain:         outputFeedback(options),
 		callback:        options.Callback,
+		longTerm:        options.LongTermMemory,
+		msgTypes:        options.MsgTypes,
 
-		MaxIterations:    options.MaxIterations,
-		filterMemoryFunc: options.FilterMemoryFunc,
-		parseOutputFunc:  options.ParseOutputFunc,
+		MaxIterations:           options.MaxIterations,
+		toolTimeout:             options.ToolTimeout,
+		filterMemoryFunc:        options.FilterMemoryFunc,
+		parseOutputFunc:         options.ParseOutputFunc,
+		filterMemoryContextFunc: options.FilterMemoryContextFunc,
 
 		prompt: template,
 		vars:   options.Vars,
@@ -89,14 +98,27 @@ func NewBaseAgent(opts ...Option) (*BaseAgent, error) {
 	return base, nil
 }
 
+// filterMemory applies the memory filter, the one given the context first.
+func (ba *BaseAgent) filterMemory(ctx context.Context, messages []schema.Message) []schema.Message {
+	if ba.filterMemoryContextFunc != nil {
+		return ba.filterMemoryContextFunc(ctx, messages)
+	}
+	if ba.filterMemoryFunc != nil {
+		return ba.filterMemoryFunc(messages)
+	}
+	return messages
+}
+
 func (ba *BaseAgent) Run(ctx context.Context,
 	messages []schema.Message, opts ...llm.GenerateOption) (*schema.Generation, error) {
 	steps := make([]schema.StepAction, 0)
 	tokens := 0
-	if ba.filterMemoryFunc != nil {
-		messages = ba.filterMemoryFunc(messages)
-	}
+	messages = ba.filterMemory(ctx, messages)
+	ctx = ba.withMemories(ctx, messages)
 	for i := 0; i < ba.MaxIterations; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		feedbacks, actions, msgs, cost, err := ba.Plan(
 			ctx, messages, steps, opts...)
 		if err != nil {
@@ -163,11 +185,16 @@ func (ba *BaseAgent) Plan(ctx context.Context, messages []schema.Message,
 	inputs["history"] = schema.ConvertConstructScratchPad(ba.name, "me", messages, steps)
 	inputs["current"] = time.Now().Format("2006-01-02 15:04:05")
 
-	if ba.env != nil {
-		inputs["agent_names"] = schema.ConvertAgentNames(ba.env.GetSubscribeAgents(ctx, ba))
-		inputs["agent_descriptions"] = schema.ConvertAgentDescriptions(ba.env.GetSubscribeAgents(ctx, ba))
-		inputs["sop"] = ba.env.SOP()
+	if env := schema.EnvOf(ctx, ba); env != nil {
+		inputs["agent_names"] = schema.ConvertAgentNames(env.GetSubscribeAgents(ctx, ba))
+		inputs["agent_descriptions"] = schema.ConvertAgentDescriptions(env.GetSubscribeAgents(ctx, ba))
+		inputs["sop"] = env.SOP()
+		if board := schema.BlackboardOf(env); board != nil {
+			inputs["blackboard"] = schema.ConvertBlackboard(board.List())
+		}
 	}
+	inputs["memories"] = ba.memories(ctx, messages)
+	inputs["msg_types"] = schema.ConvertMsgTypes(ba.msgTypes)
 
 	p, err := ba.prompt.Format(inputs)
 	if err != nil {
@@ -190,6 +217,9 @@ func (ba *BaseAgent) Plan(ctx context.Context, messages []schema.Message,
 
 	feedbacks := make([]schema.StepFeedback, 0)
 	actions, content, err := ba.parseOutputFunc(ba.name, output)
+	if err == nil {
+		err = checkMsgTypes(schema.EnvOf(ctx, ba), content, ba.msgTypes)
+	}
 	if err != nil {
 		feedbacks = append(feedbacks, schema.StepFeedback{
 			Feedback: "parse output failed with error: " + err.Error(),
@@ -198,6 +228,9 @@ func (ba *BaseAgent) Plan(ctx context.Context, messages []schema.Message,
 		return feedbacks, actions, content, output.Usage.TotalTokens, nil
 	}
 	fd := ba.fdChain.Feedback(ctx, ba, content, actions, steps, p)
+	if fh, ok := ba.callback.(callback.FeedbackHandler); ok {
+		fh.HandleFeedback(ctx, ba.Name(), fd.Type, fd.Msg)
+	}
 	if fd.Type == feedback.NotApproved {
 		feedbacks = append(feedbacks, schema.StepFeedback{
 			Feedback: fd.Msg,
@@ -225,7 +258,7 @@ func (ba *BaseAgent) doAction(
 		return
 	}
 
-	action.Observation, err = t.Call(ctx, action.Input)
+	action.Observation, err = ba.callTool(schema.ContextWithAgent(ctx, ba.Name()), t, action.Input)
 	if err != nil {
 		action.Feedback = err.Error()
 	}
@@ -235,6 +268,20 @@ func (ba *BaseAgent) doAction(
 	}
 }
 
+// callTool calls t within the tool timeout of the agent.
+func (ba *BaseAgent) callTool(ctx context.Context, t tool.Tool, input string) (string, error) {
+	if ba.toolTimeout <= 0 {
+		return t.Call(ctx, input)
+	}
+	callCtx, cancel := context.WithTimeout(ctx, ba.toolTimeout)
+	defer cancel()
+	observation, err := t.Call(callCtx, input)
+	if ctx.Err() == nil && callCtx.Err() != nil {
+		return observation, fmt.Errorf("%s timed out after %s", t.Name(), ba.toolTimeout)
+	}
+	return observation, err
+}
+
 func (ba *BaseAgent) getAction(name string) tool.Tool {
 	for _, a := range ba.tools {
 		if strings.EqualFold(a.Name(), name) {
@@ -275,7 +322,7 @@ func parseOutput(name string, output *llm.Generation) ([]schema.StepAction, []sc
 	if content == "" {
 		return nil, nil, errors.New("content is empty")
 	}
-	content = json.TrimJsonString(content)
+	content = json.RepairJsonString(content)
 	action, err := parseAction(content)
 	if err != nil {
 		return nil, nil, err
@@ -332,12 +379,95 @@ func parseAction(content string) (*schema.StepAction, error) {
 
 func parseMessage(name, content string) (*schema.Message, error) {
 	message := &schema.Message{Log: content, Sender: name}
+	// fix: content may be json instead of json string, e.g. a structured
+	// final answer
+	fields := make(map[string]any)
+	if err := json.Unmarshal([]byte(content), &fields); err == nil {
+		if value, ok := fields["content"]; ok && value != nil {
+			if _, ok = value.(string); !ok {
+				marshal, _ := json.Marshal(value)
+				fields["content"] = string(marshal)
+				bytes, _ := json.Marshal(fields)
+				content = string(bytes)
+			}
+		}
+	}
 	if err := json.Unmarshal([]byte(content), message); err != nil {
 		return nil, err
 	}
 	return message, nil
 }
 
+// checkMsgTypes reports a message of a type neither built-in, registered
+// in env nor declared by the agent, which the environment would not handle.
+func checkMsgTypes(env schema.Environment, messages []schema.Message, types []schema.MsgType) error {
+	for _, msg := range messages {
+		if msg.IsMsg() || msg.IsEnd() || msg.IsCreative() || msg.IsSOP() {
+			continue
+		}
+		if _, ok := schema.LookupMsgType(env, msg.Type); ok {
+			continue
+		}
+		names := []string{"msg", "end"}
+		for _, t := range types {
+			if strings.EqualFold(t.Name, msg.Type) {
+				names = nil
+				break
+			}
+			names = append(names, strings.ToLower(t.Name))
+		}
+		if names != nil {
+			return fmt.Errorf("cate %s is not one of [%s]", msg.Type, strings.Join(names, ", "))
+		}
+	}
+	return nil
+}
+
+type memoriesKey struct{}
+
+// withMemories recalls the facts relevant to messages once for a run, the
+// plans of the run reading them from the returned context.
+func (ba *BaseAgent) withMemories(ctx context.Context, messages []schema.Message) context.Context {
+	return context.WithValue(ctx, memoriesKey{}, ba.recall(ctx, messages))
+}
+
+// memories returns the facts recalled for the run, or recalls them when
+// planning out of a run.
+func (ba *BaseAgent) memories(ctx context.Context, messages []schema.Message) string {
+	if memories, ok := ctx.Value(memoriesKey{}).(string); ok {
+		return memories
+	}
+	return ba.recall(ctx, messages)
+}
+
+// recall returns the facts of the long-term memory relevant to the last
+// message. A failure is told to the callback, the agent going on without
+// them.
+func (ba *BaseAgent) recall(ctx context.Context, messages []schema.Message) string {
+	if ba.longTerm == nil || len(messages) == 0 {
+		return ""
+	}
+	query := messages[len(messages)-1].Content
+	if ba.callback != nil {
+		ba.callback.HandleRetrieverStart(ctx, query)
+	}
+	docs, err := ba.longTerm.Recall(ctx, ba.name, query)
+	if err != nil {
+		if eh, ok := ba.callback.(callback.ErrorHandler); ok {
+			eh.HandleError(ctx, fmt.Errorf("long-term memory of %s: %w", ba.name, err))
+		}
+		return ""
+	}
+	if ba.callback != nil {
+		ba.callback.HandleRetrieverEnd(ctx, query, docs)
+	}
+	return longterm.Format(docs)
+}
+
+func (ba *BaseAgent) LongTermMemory() *longterm.Memory {
+	return ba.longTerm
+}
+
 func (ba *BaseAgent) Name() string {
 	return ba.name
 }
@@ -354,6 +484,11 @@ func (ba *BaseAgent) Env() schema.Environment {
 	return ba.env
 }
 
+// MsgTypes returns the custom message types the agent declares.
+func (ba *BaseAgent) MsgTypes() []schema.MsgType {
+	return ba.msgTypes
+}
+
 func (ba *BaseAgent) Tools() []tool.Tool {
 	return ba.tools
 }
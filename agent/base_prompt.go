@@ -30,6 +30,20 @@ This is the SOP for the entire troubleshooting process.
 ~~~
 {{end}}
 
+{{if .blackboard}}
+This is the blackboard shared by your team, the authoritative state of the task. Read and update it with the blackboard tools, instead of copying it into your messages:
+~~~
+{{.blackboard}}
+~~~
+{{end}}
+
+{{if .memories}}
+What you remember from your previous tasks, it may be relevant:
+~~~
+{{.memories}}
+~~~
+{{end}}
+
 You have access to the following tools:
 ~~~
 {{.tool_descriptions}}
@@ -63,7 +77,18 @@ When you have final answer for user's task、question, you MUST response with js
     "content": "The final answer to the original input question"
 }
 ~~~
-
+{{if .msg_types}}
+Besides msg and end, you can send the messages of the cates below, with the json format of a msg and the cate set to one of them:
+~~~
+{{.msg_types}}
+~~~
+{{end}}
+{{if .output_schema}}
+The content of final answer MUST be a json matching the schema below:
+~~~
+{{.output_schema}}
+~~~
+{{end}}
 
 You need to make the best judgment based on the question, using tools, answering the question, or transferring the task.
 `
@@ -5,8 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/antgroup/aievo/callback"
 	"github.com/antgroup/aievo/driver"
 	"github.com/antgroup/aievo/feedback"
 	"github.com/antgroup/aievo/llm"
@@ -22,6 +24,8 @@ type GraphAgent struct {
 	BaseAgent
 	sop    string
 	Driver driver.Driver
+
+	mu sync.Mutex
 }
 
 func NewGraphAgent(opts ...Option) (*GraphAgent, error) {
@@ -75,12 +79,16 @@ func NewGraphAgent(opts ...Option) (*GraphAgent, error) {
 			env:             options.Env,
 			tools:           options.Tools,
 			useFunctionCall: options.useFunctionCall,
-			fdChain:         options.FeedbackChain,
+			fdChain:         outputFeedback(options),
 			callback:        options.Callback,
+			longTerm:        options.LongTermMemory,
+			msgTypes:        options.MsgTypes,
 
-			MaxIterations:    options.MaxIterations,
-			filterMemoryFunc: options.FilterMemoryFunc,
-			parseOutputFunc:  options.ParseOutputFunc,
+			MaxIterations:           options.MaxIterations,
+			toolTimeout:             options.ToolTimeout,
+			filterMemoryFunc:        options.FilterMemoryFunc,
+			parseOutputFunc:         options.ParseOutputFunc,
+			filterMemoryContextFunc: options.FilterMemoryContextFunc,
 
 			prompt: template,
 			vars:   options.Vars,
@@ -94,6 +102,8 @@ func NewGraphAgent(opts ...Option) (*GraphAgent, error) {
 
 func (ba *GraphAgent) InitGraph(ctx context.Context) error {
 	var err error
+	ba.mu.Lock()
+	defer ba.mu.Unlock()
 	if ba.sop == "" && ba.Env() != nil && ba.Env().SOP() != "" {
 		ba.sop = ba.Env().SOP()
 	}
@@ -109,19 +119,102 @@ func (ba *GraphAgent) InitGraph(ctx context.Context) error {
 	return nil
 }
 
+// sessionEnv returns the environment of ctx when it is not the one of the
+// agent and keeps the agent states, e.g. the one of a session, whose graph
+// progress is kept there and goes away with it.
+func (ba *GraphAgent) sessionEnv(ctx context.Context) schema.AgentStateEnvironment {
+	env := schema.EnvFromContext(ctx)
+	if env == nil || env == ba.env {
+		return nil
+	}
+	se, _ := env.(schema.AgentStateEnvironment)
+	return se
+}
+
+// driver returns the driver tracking the graph of the environment of ctx.
+func (ba *GraphAgent) driver(ctx context.Context) (driver.Driver, error) {
+	se := ba.sessionEnv(ctx)
+	if se == nil {
+		if err := ba.InitGraph(ctx); err != nil {
+			return nil, err
+		}
+		return ba.Driver, nil
+	}
+	ba.mu.Lock()
+	defer ba.mu.Unlock()
+	if dri, ok := se.AgentState(ba).(driver.Driver); ok {
+		return dri, nil
+	}
+	sop := ba.sop
+	if sop == "" {
+		sop = schema.EnvFromContext(ctx).SOP()
+	}
+	dri, err := driver.NewGraphDriver().InitGraph(ctx, sop)
+	if err != nil {
+		return nil, errors.New("graph sop parse failed, err: " + err.Error())
+	}
+	se.SetAgentState(ba, dri)
+	return dri, nil
+}
+
+var _ schema.StatefulAgent = (*GraphAgent)(nil)
+
+// SaveState returns the progress on the SOP in the environment of ctx, or
+// nil when the graph is not initialized yet.
+func (ba *GraphAgent) SaveState(ctx context.Context) ([]byte, error) {
+	dri, err := ba.driver(ctx)
+	if err != nil || !dri.IsInit() {
+		return nil, nil
+	}
+	sd, ok := dri.(driver.StatefulDriver)
+	if !ok {
+		return nil, nil
+	}
+	return json.Marshal(sd.State())
+}
+
+// LoadState restores the progress on the SOP in the environment of ctx.
+func (ba *GraphAgent) LoadState(ctx context.Context, state []byte) error {
+	if len(state) == 0 {
+		return nil
+	}
+	graph := &driver.GraphState{}
+	if err := json.Unmarshal(state, graph); err != nil {
+		return err
+	}
+	dri := driver.NewGraphDriver()
+	if err := dri.Restore(ctx, graph); err != nil {
+		return err
+	}
+	ba.mu.Lock()
+	defer ba.mu.Unlock()
+	se := ba.sessionEnv(ctx)
+	if se == nil {
+		ba.Driver = dri
+		if ba.sop == "" {
+			ba.sop = graph.SOP
+		}
+		return nil
+	}
+	se.SetAgentState(ba, dri)
+	return nil
+}
+
 func (ba *GraphAgent) Run(ctx context.Context,
 	messages []schema.Message, opts ...llm.GenerateOption) (*schema.Generation, error) {
 	// 初始化graph, 避免graph是由env传入的
-	err := ba.InitGraph(ctx)
+	dri, err := ba.driver(ctx)
 	if err != nil {
 		return nil, err
 	}
 	steps := make([]schema.StepAction, 0)
 	tokens := 0
-	if ba.filterMemoryFunc != nil {
-		messages = ba.filterMemoryFunc(messages)
-	}
+	messages = ba.filterMemory(ctx, messages)
+	ctx = ba.withMemories(ctx, messages)
 	for i := 0; i < ba.MaxIterations; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		// 获取当前已经执行的graph
 		feedbacks, actions, msgs, cost, err := ba.Plan(
 			ctx, messages, steps, opts...)
@@ -166,7 +259,7 @@ func (ba *GraphAgent) Run(ctx context.Context,
 			}, nil
 		}
 		// 更新graph 状态
-		ba.Driver.UpdateGraphState(ctx, steps, actions)
+		dri.UpdateGraphState(ctx, steps, actions)
 	}
 	return nil, schema.ErrNotFinished
 }
@@ -192,15 +285,24 @@ func (ba *GraphAgent) Plan(ctx context.Context, messages []schema.Message,
 	inputs["history"] = schema.ConvertConstructScratchPad(ba.name, "me", messages, nil)
 	inputs["current"] = time.Now().Format("2006-01-02 15:04:05")
 
+	dri, err := ba.driver(ctx)
+	if err != nil {
+		return nil, nil, nil, 0, err
+	}
 	inputs["current_nodes"], inputs["next_nodes"],
-		inputs["all_nodes"] = ba.Driver.RenderStates()
+		inputs["all_nodes"] = dri.RenderStates()
 
 	inputs["sop"] = ba.sop
-	inputs["current_sop"], _ = ba.Driver.RenderCurrentGraph()
-	if ba.env != nil {
-		inputs["agent_names"] = schema.ConvertAgentNames(ba.env.GetSubscribeAgents(ctx, ba))
-		inputs["agent_descriptions"] = schema.ConvertAgentDescriptions(ba.env.GetSubscribeAgents(ctx, ba))
+	inputs["current_sop"], _ = dri.RenderCurrentGraph()
+	if env := schema.EnvOf(ctx, ba); env != nil {
+		inputs["agent_names"] = schema.ConvertAgentNames(env.GetSubscribeAgents(ctx, ba))
+		inputs["agent_descriptions"] = schema.ConvertAgentDescriptions(env.GetSubscribeAgents(ctx, ba))
+		if board := schema.BlackboardOf(env); board != nil {
+			inputs["blackboard"] = schema.ConvertBlackboard(board.List())
+		}
 	}
+	inputs["memories"] = ba.memories(ctx, messages)
+	inputs["msg_types"] = schema.ConvertMsgTypes(ba.msgTypes)
 
 	p, err := ba.prompt.Format(inputs)
 	if err != nil {
@@ -223,6 +325,9 @@ func (ba *GraphAgent) Plan(ctx context.Context, messages []schema.Message,
 
 	feedbacks := make([]schema.StepFeedback, 0)
 	actions, content, err := ba.parseOutputFunc(ba.name, output)
+	if err == nil {
+		err = checkMsgTypes(schema.EnvOf(ctx, ba), content, ba.msgTypes)
+	}
 	if err != nil {
 		feedbacks = append(feedbacks, schema.StepFeedback{
 			Feedback: "parse output failed with error: " + err.Error(),
@@ -231,6 +336,9 @@ func (ba *GraphAgent) Plan(ctx context.Context, messages []schema.Message,
 		return feedbacks, actions, content, output.Usage.TotalTokens, nil
 	}
 	fd := ba.fdChain.Feedback(ctx, ba, content, actions, steps, p)
+	if fh, ok := ba.callback.(callback.FeedbackHandler); ok {
+		fh.HandleFeedback(ctx, ba.Name(), fd.Type, fd.Msg)
+	}
 	if fd.Type == feedback.NotApproved {
 		feedbacks = append(feedbacks, schema.StepFeedback{
 			Feedback: fd.Msg,
@@ -258,7 +366,7 @@ func (ba *GraphAgent) doAction(
 		return
 	}
 
-	action.Observation, err = t.Call(ctx, action.Input)
+	action.Observation, err = ba.callTool(schema.ContextWithAgent(ctx, ba.Name()), t, action.Input)
 	if err != nil {
 		action.Feedback = err.Error()
 	}
@@ -305,7 +413,7 @@ func parseGraphOutput(name string, output *llm.Generation) ([]schema.StepAction,
 	if content == "" {
 		return nil, nil, errors.New("content is empty")
 	}
-	content = json.TrimJsonString(content)
+	content = json.RepairJsonString(content)
 	actions, _ := parseActionArray(content)
 	if len(actions) != 0 {
 		return actions, nil, nil
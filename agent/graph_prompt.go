@@ -25,6 +25,20 @@ This is the SOP for user task.
 ~~~
 {{end}}
 
+{{if .blackboard}}
+This is the blackboard shared by your team, the authoritative state of the task. Read and update it with the blackboard tools, instead of copying it into your messages:
+~~~
+{{.blackboard}}
+~~~
+{{end}}
+
+{{if .memories}}
+What you remember from your previous tasks, it may be relevant:
+~~~
+{{.memories}}
+~~~
+{{end}}
+
 {{if .current_sop}}
 You have executed the following nodes in sop
 ~~~
@@ -66,7 +80,18 @@ When you have final answer for user's task、question, or you want to ask user f
     "content": "The final answer to the original input question"
 }
 ~~~
-`
+{{if .msg_types}}
+Besides msg and end, you can send the messages of the cates below, with the json format of a msg and the cate set to one of them:
+~~~
+{{.msg_types}}
+~~~
+{{end}}
+{{if .output_schema}}
+The content of final answer MUST be a json matching the schema below:
+~~~
+{{.output_schema}}
+~~~
+{{end}}`
 
 const _defaultGraphSuffix = `
 Previous conversation:
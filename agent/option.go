@@ -1,12 +1,17 @@
 package agent
 
 import (
+	"context"
+	"time"
+
 	"github.com/antgroup/aievo/callback"
 	"github.com/antgroup/aievo/driver"
 	"github.com/antgroup/aievo/feedback"
 	"github.com/antgroup/aievo/llm"
+	"github.com/antgroup/aievo/memory/longterm"
 	"github.com/antgroup/aievo/schema"
 	"github.com/antgroup/aievo/tool"
+	"github.com/antgroup/aievo/utils/json"
 )
 
 type Option func(opt *Options)
@@ -31,12 +36,19 @@ type Options struct {
 	Env              schema.Environment
 	Callback         callback.Handler
 	FilterMemoryFunc func([]schema.Message) []schema.Message
-	ParseOutputFunc  func(string, *llm.Generation) ([]schema.StepAction, []schema.Message, error)
-	Vars             map[string]string
-	SOPGraph         string
-	Driver           driver.Driver
+	// FilterMemoryContextFunc is FilterMemoryFunc given the context of the
+	// run, used instead of it when set
+	FilterMemoryContextFunc func(context.Context, []schema.Message) []schema.Message
+	ParseOutputFunc         func(string, *llm.Generation) ([]schema.StepAction, []schema.Message, error)
+	Vars                    map[string]string
+	SOPGraph                string
+	Driver                  driver.Driver
+	OutputSchema            *tool.PropertiesSchema
+	LongTermMemory          *longterm.Memory
+	MsgTypes                []schema.MsgType
 
 	MaxIterations int
+	ToolTimeout   time.Duration
 }
 
 func WithName(name string) Option {
@@ -111,6 +123,14 @@ func WithMaxIterations(maxIterations int) Option {
 	}
 }
 
+// WithToolTimeout limits the time of each tool call, a call timing out is
+// reported to the agent as a feedback.
+func WithToolTimeout(timeout time.Duration) Option {
+	return func(opt *Options) {
+		opt.ToolTimeout = timeout
+	}
+}
+
 func WithCallback(callback callback.Handler) Option {
 	return func(opt *Options) {
 		opt.Callback = callback
@@ -126,6 +146,16 @@ func WithVars(k, v string) Option {
 	}
 }
 
+// WithOutputSchema requires the content of the final answer to be a json
+// matching schema, violations are sent back to the agent as feedback.
+func WithOutputSchema(schema *tool.PropertiesSchema) Option {
+	return func(opt *Options) {
+		opt.OutputSchema = schema
+		bytes, _ := json.Marshal(schema)
+		WithVars("output_schema", string(bytes))(opt)
+	}
+}
+
 func WithSOPGraph(sop string) Option {
 	return func(opt *Options) {
 		opt.SOPGraph = sop
@@ -138,18 +168,53 @@ func WithFilterMemoryFunc(fun func([]schema.Message) []schema.Message) Option {
 	}
 }
 
+// WithContextFilterMemoryFunc is WithFilterMemoryFunc for a filter calling
+// an LLM or a store, e.g. memory.Summarizer.FilterFunc, which is cancelled
+// with the run.
+func WithContextFilterMemoryFunc(fun func(context.Context, []schema.Message) []schema.Message) Option {
+	return func(opt *Options) {
+		opt.FilterMemoryContextFunc = fun
+	}
+}
+
 func WithParseOutputFunc(fun func(string, *llm.Generation) ([]schema.StepAction, []schema.Message, error)) Option {
 	return func(opt *Options) {
 		opt.ParseOutputFunc = fun
 	}
 }
 
+// WithLongTermMemory gives the agent a memory across runs: the facts
+// relevant to its messages are recalled into the {{.memories}} prompt
+// variable, and the team remembers the facts of each run.
+func WithLongTermMemory(memory *longterm.Memory) Option {
+	return func(opt *Options) {
+		opt.LongTermMemory = memory
+	}
+}
+
+// WithMsgTypes declares the custom message types the agent may send, given
+// to its prompt in the {{.msg_types}} variable. The messages of other types
+// than these and the built-in ones are sent back as feedback.
+func WithMsgTypes(types ...schema.MsgType) Option {
+	return func(opt *Options) {
+		opt.MsgTypes = append(opt.MsgTypes, types...)
+	}
+}
+
 func WithDriver(dri driver.Driver) Option {
 	return func(opt *Options) {
 		opt.Driver = dri
 	}
 }
 
+func outputFeedback(options *Options) feedback.Feedback {
+	if options.OutputSchema == nil {
+		return options.FeedbackChain
+	}
+	return feedback.Chain(feedback.NewSchemaFeedback(options.OutputSchema),
+		options.FeedbackChain)
+}
+
 func defaultBaseOptions() []Option {
 	return []Option{
 		WithPrompt(_defaultBasePrompt),
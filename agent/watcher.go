@@ -1,8 +1,6 @@
 package agent
 
 import (
-	"strings"
-
 	"github.com/antgroup/aievo/llm"
 	"github.com/antgroup/aievo/schema"
 	"github.com/antgroup/aievo/utils/json"
@@ -18,7 +16,7 @@ func parseMngInfoOutput(_ string, output *llm.Generation) ([]schema.StepAction,
 	if len(output.ToolCalls) > 0 {
 		return parseToolCalls(output.ToolCalls), nil, nil
 	}
-	content := json.TrimJsonString(strings.TrimSpace(output.Content))
+	content := json.RepairJsonString(output.Content)
 	action, err := parseAction(content)
 	if err != nil {
 		return nil, nil, err
@@ -0,0 +1,100 @@
+package aievo
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/antgroup/aievo/callback"
+	"github.com/antgroup/aievo/environment"
+	"github.com/antgroup/aievo/llm"
+	"github.com/antgroup/aievo/schema"
+	"github.com/antgroup/aievo/tool"
+)
+
+var _ schema.Agent = (*TeamAgent)(nil)
+
+// TeamAgent adapts a whole team to schema.Agent, so it can be a single
+// member of another team. The nested team keeps its own Environment and
+// Memory, which are reset before each run.
+type TeamAgent struct {
+	name string
+	desc string
+	team *AIEvo
+	env  schema.Environment
+
+	mu sync.Mutex
+}
+
+// NewTeamAgent wraps team as an agent called name. Callback events of the
+// nested team are reported with agent names prefixed by name.
+func NewTeamAgent(team *AIEvo, name, desc string) *TeamAgent {
+	if team.Callback != nil {
+		team.Callback = callback.NewScopeHandler(name, team.Callback)
+	}
+	return &TeamAgent{
+		name: name,
+		desc: desc,
+		team: team,
+	}
+}
+
+func (t *TeamAgent) Run(ctx context.Context, messages []schema.Message,
+	opts ...llm.GenerateOption) (*schema.Generation, error) {
+	if len(messages) == 0 {
+		return nil, errors.New("no messages provided")
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.team.Reset(ctx); err != nil {
+		return nil, err
+	}
+	message := messages[len(messages)-1]
+	content, err := t.team.Run(ctx, message.Content, opts...)
+	if err != nil {
+		return nil, err
+	}
+	tokens := t.team.Token()
+	return &schema.Generation{
+		Messages: []schema.Message{{
+			Type:     schema.MsgTypeMsg,
+			Content:  content,
+			Sender:   t.name,
+			Receiver: message.Sender,
+			Token:    tokens,
+		}},
+		TotalTokens: tokens,
+	}, nil
+}
+
+func (t *TeamAgent) Name() string {
+	return t.name
+}
+
+func (t *TeamAgent) Description() string {
+	return t.desc
+}
+
+// WithEnv sets the environment of the outer team. When the nested team has
+// no callback of its own, it reports to the outer team's callback.
+func (t *TeamAgent) WithEnv(env schema.Environment) {
+	t.env = env
+	outer, ok := env.(*environment.Environment)
+	if ok && outer.Callback != nil && t.team.Callback == nil {
+		t.team.Callback = callback.NewScopeHandler(t.name, outer.Callback)
+	}
+}
+
+func (t *TeamAgent) Env() schema.Environment {
+	return t.env
+}
+
+func (t *TeamAgent) Tools() []tool.Tool {
+	return nil
+}
+
+// Team returns the nested team.
+func (t *TeamAgent) Team() *AIEvo {
+	return t.team
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/antgroup/aievo/environment"
 	"github.com/antgroup/aievo/llm"
 	"github.com/antgroup/aievo/memory"
+	"github.com/antgroup/aievo/schema"
 )
 
 var (
@@ -51,6 +52,21 @@ func initializeAIEvo(e *AIEvo, o *options) {
 	e.Planner = o.planner
 	e.Watcher = o.watcher
 	e.WatchCondition = o.watchCondition
+	e.ResultSchema = o.resultSchema
+	e.ParallelDispatch = o.parallel
+	e.TurnTimeout = o.turnTimeout
+	e.Scheduling = o.scheduling
+	e.Checkpoints = o.checkpoints
+	if o.visibility != nil {
+		e.Visibility = o.visibility
+	}
+	for msgType, strategy := range o.strategies {
+		e.RegisterStrategy(msgType, strategy)
+	}
+	e.RegisterMsgType(o.msgTypes...)
+	if len(o.termination) != 0 {
+		e.Termination = Or(o.termination...)
+	}
 	e.Handler = Chain(e.BuildPlan, e.BuildSOP, e.Watch, e.Scheduler)
 }
 
@@ -77,5 +93,8 @@ func initializeTeam(e *AIEvo, o *options) {
 }
 
 func (e *AIEvo) Run(ctx context.Context, prompt string, opts ...llm.GenerateOption) (string, error) {
-	return e.Handler(ctx, prompt, opts...)
+	// stops the watcher when the run ends
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	return e.memorize(schema.ContextWithEnv(ctx, e.Environment), e.Handler, prompt, opts...)
 }
@@ -0,0 +1,750 @@
+package aievo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antgroup/aievo/callback"
+	"github.com/antgroup/aievo/environment"
+	"github.com/antgroup/aievo/llm"
+	"github.com/antgroup/aievo/memory"
+	"github.com/antgroup/aievo/memory/longterm"
+	"github.com/antgroup/aievo/schema"
+	"github.com/antgroup/aievo/tool"
+	"github.com/antgroup/aievo/transcript"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptAgent answers with the messages returned by reply.
+type scriptAgent struct {
+	name  string
+	env   schema.Environment
+	reply func(messages []schema.Message) []schema.Message
+}
+
+func newScriptAgent(name string, reply func([]schema.Message) []schema.Message) *scriptAgent {
+	return &scriptAgent{name: name, reply: reply}
+}
+
+func (a *scriptAgent) Run(_ context.Context, messages []schema.Message,
+	_ ...llm.GenerateOption) (*schema.Generation, error) {
+	msgs := a.reply(messages)
+	for i := range msgs {
+		msgs[i].Sender = a.name
+		msgs[i].Token = 1
+	}
+	return &schema.Generation{Messages: msgs, TotalTokens: len(msgs)}, nil
+}
+
+func (a *scriptAgent) Name() string                   { return a.name }
+func (a *scriptAgent) Description() string            { return a.name }
+func (a *scriptAgent) WithEnv(env schema.Environment) { a.env = env }
+func (a *scriptAgent) Env() schema.Environment        { return a.env }
+func (a *scriptAgent) Tools() []tool.Tool             { return nil }
+
+func last(messages []schema.Message) schema.Message {
+	return messages[len(messages)-1]
+}
+
+func end(content string) []schema.Message {
+	return []schema.Message{{Type: schema.MsgTypeEnd, Content: content}}
+}
+
+func send(receiver, content string) []schema.Message {
+	return []schema.Message{{Type: schema.MsgTypeMsg, Receiver: receiver, Content: content}}
+}
+
+func TestTeamAgent(t *testing.T) {
+	searcher := newScriptAgent("searcher", func(messages []schema.Message) []schema.Message {
+		return send("writer", "found: "+last(messages).Content)
+	})
+	writer := newScriptAgent("writer", func(messages []schema.Message) []schema.Message {
+		return end("report on " + last(messages).Content)
+	})
+	research, err := NewAIEvo(
+		WithTeam([]schema.Agent{searcher, writer}),
+		WithTeamLeader(searcher),
+		WithSubScribeMode(environment.ALLSubMode))
+	require.NoError(t, err)
+	department := NewTeamAgent(research, "Research", "research department")
+
+	leader := newScriptAgent("leader", func(messages []schema.Message) []schema.Message {
+		msg := last(messages)
+		if msg.Sender == "Research" {
+			return end(msg.Content)
+		}
+		return send("Research", msg.Content)
+	})
+	company, err := NewAIEvo(
+		WithTeam([]schema.Agent{leader, department}),
+		WithTeamLeader(leader))
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		result, err := company.Run(context.Background(), "golang")
+		require.NoError(t, err)
+		assert.Equal(t, "report on found: golang", result)
+		assert.Equal(t, 2, research.Token())
+	}
+}
+
+func TestRunTyped(t *testing.T) {
+	type answer struct {
+		City  string `json:"city"`
+		Score int    `json:"score"`
+	}
+	feedbacks := make([]string, 0)
+	analyst := newScriptAgent("analyst", func(messages []schema.Message) []schema.Message {
+		msg := last(messages)
+		if msg.Content == "rank the cities" {
+			return end("The best city is Hangzhou with 9 points")
+		}
+		feedbacks = append(feedbacks, msg.Content)
+		return end("```json\n{'city': 'Hangzhou', 'score': 9,}\n```")
+	})
+	team, err := NewAIEvo(
+		WithTeam([]schema.Agent{analyst}),
+		WithTeamLeader(analyst),
+		WithResultSchema(&tool.PropertiesSchema{
+			Type: tool.TypeJson,
+			Properties: map[string]tool.PropertySchema{
+				"city":  {Type: tool.TypeString},
+				"score": {Type: tool.TypeInt},
+			},
+			Required: []string{"city", "score"},
+		}))
+	require.NoError(t, err)
+
+	result, err := RunTyped[answer](context.Background(), team, "rank the cities")
+	require.NoError(t, err)
+	assert.Equal(t, answer{City: "Hangzhou", Score: 9}, result)
+	require.Len(t, feedbacks, 1)
+	assert.Contains(t, feedbacks[0], "does not match the output schema")
+
+	// no final answer is not reported as an invalid one
+	pong := newScriptAgent("pong", func([]schema.Message) []schema.Message {
+		return send("analyst", "pong")
+	})
+	loop := newScriptAgent("analyst", func([]schema.Message) []schema.Message {
+		return send("pong", "ping")
+	})
+	team, err = NewAIEvo(WithTeam([]schema.Agent{loop, pong}), WithTeamLeader(loop), WithMaxTurn(3))
+	require.NoError(t, err)
+	_, err = RunTyped[answer](context.Background(), team, "rank the cities")
+	assert.ErrorIs(t, err, ErrMaxTurnsExceeded)
+	assert.NotErrorIs(t, err, ErrInvalidResult)
+}
+
+func TestParallelDispatch(t *testing.T) {
+	started := make(chan struct{}, 3)
+	players := make([]schema.Agent, 0, 3)
+	for _, name := range []string{"p1", "p2", "p3"} {
+		players = append(players, newScriptAgent(name, func([]schema.Message) []schema.Message {
+			started <- struct{}{}
+			// every player waits for the others, which only works when
+			// they run concurrently
+			for len(started) < 3 {
+				time.Sleep(time.Millisecond)
+			}
+			return send("god", "vote from "+name)
+		}))
+	}
+	god := newScriptAgent("god", func(messages []schema.Message) []schema.Message {
+		if last(messages).Sender == "User" {
+			return send("ALL", "please vote")
+		}
+		votes := make([]string, 0)
+		for _, msg := range messages {
+			if strings.HasPrefix(msg.Content, "vote from") {
+				votes = append(votes, msg.Content)
+			}
+		}
+		return end(strings.Join(votes, ";"))
+	})
+	team, err := NewAIEvo(
+		WithTeam(append([]schema.Agent{god}, players...)),
+		WithTeamLeader(god),
+		WithSubScribeMode(environment.ALLSubMode),
+		WithParallelDispatch(3))
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	var result string
+	go func() {
+		defer close(done)
+		result, err = team.Run(context.Background(), "start")
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("players are not run concurrently")
+	}
+	require.NoError(t, err)
+	assert.Equal(t, "vote from p1;vote from p2;vote from p3", result)
+}
+
+func TestSession(t *testing.T) {
+	echo := newScriptAgent("echo", func(messages []schema.Message) []schema.Message {
+		prompts := make([]string, 0)
+		for _, msg := range messages {
+			if msg.Sender == "User" {
+				prompts = append(prompts, msg.Content)
+			}
+		}
+		return end(strings.Join(prompts, ","))
+	})
+	team, err := NewAIEvo(WithTeam([]schema.Agent{echo}), WithTeamLeader(echo))
+	require.NoError(t, err)
+
+	sessions := []*Session{team.NewSession(), team.NewSession()}
+	results := make([][]string, len(sessions))
+	done := make(chan struct{})
+	for i, session := range sessions {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			for j := 0; j < 3; j++ {
+				result, err := session.Send(context.Background(), fmt.Sprintf("s%d-%d", i, j))
+				assert.NoError(t, err)
+				results[i] = append(results[i], result)
+			}
+		}()
+	}
+	for range sessions {
+		<-done
+	}
+	assert.Equal(t, []string{"s0-0", "s0-0,s0-1", "s0-0,s0-1,s0-2"}, results[0])
+	assert.Equal(t, []string{"s1-0", "s1-0,s1-1", "s1-0,s1-1,s1-2"}, results[1])
+	assert.Equal(t, 3, sessions[0].Tokens())
+	assert.Len(t, sessions[0].Memory(context.Background()), 6)
+	assert.Empty(t, team.Memory.Load(context.Background(), nil))
+}
+
+func TestRunWithResult(t *testing.T) {
+	ping := newScriptAgent("ping", func(messages []schema.Message) []schema.Message {
+		if len(messages) >= 3 {
+			return end("done")
+		}
+		return send("pong", "ping")
+	})
+	pong := newScriptAgent("pong", func([]schema.Message) []schema.Message {
+		return send("ping", "pong")
+	})
+	team, err := NewAIEvo(WithTeam([]schema.Agent{ping, pong}), WithTeamLeader(ping))
+	require.NoError(t, err)
+
+	result, err := team.RunWithResult(context.Background(), "start")
+	require.NoError(t, err)
+	assert.Equal(t, ReasonEnd, result.Reason)
+	assert.Equal(t, "done", result.Content)
+	assert.Equal(t, map[string]int{"User": 0, "ping": 2, "pong": 1}, result.TokensByAgent)
+	assert.Len(t, result.Transcript, 4)
+	assert.Equal(t, 4, result.Turns)
+
+	// a window memory does not cut the transcript, and the second run on
+	// the team only reports its own turns
+	turns := 0
+	counter := newScriptAgent("ping", func([]schema.Message) []schema.Message {
+		if turns++; turns%2 == 0 {
+			return end("done")
+		}
+		return send("pong", "ping")
+	})
+	team, err = NewAIEvo(WithTeam([]schema.Agent{counter, pong}), WithTeamLeader(counter))
+	require.NoError(t, err)
+	team.Memory = memory.NewBufferWindowMemory(2)
+	for i := 0; i < 2; i++ {
+		result, err = team.RunWithResult(context.Background(), "start")
+		require.NoError(t, err)
+		assert.Len(t, result.Transcript, 4)
+		assert.Equal(t, 4, result.Turns)
+		assert.Equal(t, 3, result.Tokens)
+	}
+
+	// the run goes on forever without a final answer
+	loop := newScriptAgent("ping", func([]schema.Message) []schema.Message {
+		return send("pong", "ping")
+	})
+	team, err = NewAIEvo(WithTeam([]schema.Agent{loop, pong}), WithTeamLeader(loop), WithMaxTurn(5))
+	require.NoError(t, err)
+	result, err = team.RunWithResult(context.Background(), "start")
+	assert.ErrorIs(t, err, ErrMaxTurnsExceeded)
+	assert.Equal(t, ReasonMaxTurns, result.Reason)
+	assert.Equal(t, 6, result.Turns)
+
+	require.NoError(t, team.Reset(context.Background()))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, err = team.RunWithResult(ctx, "start")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, ReasonCancelled, result.Reason)
+}
+
+// blockAgent blocks until its context is done.
+type blockAgent struct {
+	scriptAgent
+}
+
+func (a *blockAgent) Run(ctx context.Context, _ []schema.Message,
+	_ ...llm.GenerateOption) (*schema.Generation, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestCancellation(t *testing.T) {
+	slow := &blockAgent{scriptAgent{name: "slow"}}
+	team, err := NewAIEvo(WithTeam([]schema.Agent{slow}), WithTeamLeader(slow),
+		WithTurnTimeout(50*time.Millisecond))
+	require.NoError(t, err)
+	result, err := team.RunWithResult(context.Background(), "start")
+	assert.ErrorIs(t, err, ErrTurnTimeout)
+	assert.Equal(t, ReasonError, result.Reason)
+
+	team.TurnTimeout = 0
+	require.NoError(t, team.Reset(context.Background()))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	result, err = team.RunWithResult(ctx, "start")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, ReasonCancelled, result.Reason)
+
+	// an agent finishing as its turn times out is not failed
+	lazy := newScriptAgent("lazy", func([]schema.Message) []schema.Message {
+		time.Sleep(100 * time.Millisecond)
+		return end("done")
+	})
+	team, err = NewAIEvo(WithTeam([]schema.Agent{lazy}), WithTeamLeader(lazy),
+		WithTurnTimeout(20*time.Millisecond))
+	require.NoError(t, err)
+	result, err = team.RunWithResult(context.Background(), "start")
+	require.NoError(t, err)
+	assert.Equal(t, "done", result.Content)
+}
+
+// stuckAgent blocks until its context is done, after telling it started.
+type stuckAgent struct {
+	scriptAgent
+	started chan struct{}
+}
+
+func (a *stuckAgent) Run(ctx context.Context, _ []schema.Message,
+	_ ...llm.GenerateOption) (*schema.Generation, error) {
+	a.started <- struct{}{}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestInterrupt(t *testing.T) {
+	worker := &stuckAgent{scriptAgent{name: "worker"}, make(chan struct{})}
+	leader := newScriptAgent("leader", func(messages []schema.Message) []schema.Message {
+		msg := last(messages)
+		if msg.Sender == "User" && msg.Content != "start" {
+			return end("corrected by " + msg.Content)
+		}
+		return send("worker", msg.Content)
+	})
+	team, err := NewAIEvo(WithTeam([]schema.Agent{leader, worker}), WithTeamLeader(leader))
+	require.NoError(t, err)
+	session := team.NewSession()
+
+	// injected between sends, the message comes before the prompt
+	require.NoError(t, session.Inject(context.Background(), schema.Message{Content: "first"}))
+	_, err = session.Send(context.Background(), "second")
+	require.NoError(t, err)
+	assert.Equal(t, "first", session.Memory(context.Background())[0].Content)
+
+	done := make(chan string)
+	go func() {
+		result, err := session.Send(context.Background(), "start")
+		assert.NoError(t, err)
+		done <- result
+	}()
+	<-worker.started
+	require.NoError(t, session.Interrupt(context.Background(), schema.Message{Content: "stop"}))
+	assert.Equal(t, "corrected by stop", <-done)
+
+	assert.ErrorIs(t, session.Inject(context.Background(),
+		schema.Message{Type: schema.MsgTypeEnd}), environment.ErrInjectType)
+}
+
+// fakeLLM answers with the given content.
+type fakeLLM struct {
+	content string
+}
+
+func (f *fakeLLM) Generate(_ context.Context, _ string, _ ...llm.GenerateOption) (*llm.Generation, error) {
+	return &llm.Generation{Content: f.content, Usage: &llm.Usage{}}, nil
+}
+
+func (f *fakeLLM) GenerateContent(ctx context.Context, _ []llm.Message, _ ...llm.GenerateOption) (*llm.Generation, error) {
+	return f.Generate(ctx, "")
+}
+
+// bidAgent says its name and bids a fixed score.
+type bidAgent struct {
+	*scriptAgent
+	bid float64
+}
+
+func (a *bidAgent) Bid(context.Context, []schema.Message) (float64, error) {
+	return a.bid, nil
+}
+
+func TestSchedulingStrategy(t *testing.T) {
+	speakers := make([]string, 0)
+	debater := func(name string) *scriptAgent {
+		return newScriptAgent(name, func(messages []schema.Message) []schema.Message {
+			speakers = append(speakers, name)
+			if len(speakers) == 5 {
+				return end(strings.Join(speakers, ","))
+			}
+			return send(schema.MsgAllReceiver, name+" speaks")
+		})
+	}
+	a, b, c := debater("a"), debater("b"), debater("c")
+	run := func(strategy SchedulingStrategy, team ...schema.Agent) string {
+		speakers = speakers[:0]
+		evo, err := NewAIEvo(WithTeam(team), WithTeamLeader(team[0]),
+			WithSubScribeMode(environment.ALLSubMode), WithSchedulingStrategy(strategy))
+		require.NoError(t, err)
+		result, err := evo.Run(context.Background(), "debate")
+		require.NoError(t, err)
+		return result
+	}
+	assert.Equal(t, "a,b,c,a,b", run(RoundRobin{}, a, b, c))
+
+	manager, err := NewManager(&fakeLLM{content: "Next speaker: **c**"})
+	require.NoError(t, err)
+	assert.Equal(t, "c,c,c,c,c", run(manager, a, b, c))
+
+	assert.Equal(t, "c,b,c,b,c", run(Bidding{}, a, &bidAgent{b, 1}, &bidAgent{c, 2}))
+}
+
+func TestTermination(t *testing.T) {
+	polite := func(name, other string) *scriptAgent {
+		return newScriptAgent(name, func([]schema.Message) []schema.Message {
+			return send(other, "could you please handle the request, "+other+"?")
+		})
+	}
+	alice, bob := polite("alice", "bob"), polite("bob", "alice")
+	team, err := NewAIEvo(WithTeam([]schema.Agent{alice, bob}), WithTeamLeader(alice),
+		WithTermination(PingPong(2, 0.9), Timeout(time.Minute)))
+	require.NoError(t, err)
+	result, err := team.RunWithResult(context.Background(), "handle the request")
+	assert.ErrorIs(t, err, ErrTerminated)
+	assert.Equal(t, ReasonTerminated, result.Reason)
+	assert.EqualError(t, err, "terminated: alice and bob repeat each other")
+	assert.Less(t, result.Turns, 10)
+
+	msgs := func(senders ...string) *TerminationState {
+		state := &TerminationState{Start: time.Now()}
+		for i, sender := range senders {
+			state.Messages = append(state.Messages,
+				schema.Message{Sender: sender, Content: fmt.Sprintf("step %d", i)})
+		}
+		return state
+	}
+	_, ok := MaxConsecutiveTurns(3).Terminate(msgs("a", "b", "b", "b"))
+	assert.True(t, ok)
+	_, ok = MaxConsecutiveTurns(3).Terminate(msgs("b", "a", "b", "b"))
+	assert.False(t, ok)
+	_, ok = NoProgress(2, 0.5).Terminate(msgs("a", "b", "c"))
+	assert.False(t, ok)
+	reason, ok := And(TextMention("step 2"), MaxConsecutiveTurns(2)).Terminate(msgs("a", "b", "b"))
+	assert.True(t, ok)
+	assert.Equal(t, `b mentioned "step 2" and b took 2 turns in a row`, reason)
+	_, ok = Or(Timeout(time.Hour), TextMention("TERMINATE")).Terminate(msgs("a"))
+	assert.False(t, ok)
+
+	state := msgs("a", "b", "a")
+	state.Messages[2].Content = "STEP 0!"
+	_, ok = NoProgress(1, 0.9).Terminate(state)
+	assert.True(t, ok)
+}
+
+func TestCheckpoint(t *testing.T) {
+	failed := errors.New("transient")
+	store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "run.json"))
+	build := func(fail bool) (*AIEvo, *int) {
+		calls := new(int)
+		ping := newScriptAgent("ping", func(messages []schema.Message) []schema.Message {
+			if len(messages) >= 5 {
+				return end("done")
+			}
+			return send("pong", "ping")
+		})
+		pong := &flakyAgent{scriptAgent: newScriptAgent("pong", func([]schema.Message) []schema.Message {
+			return send("ping", "pong")
+		}), fail: func() error {
+			*calls++
+			if fail && *calls == 2 {
+				return failed
+			}
+			return nil
+		}}
+		extra := newScriptAgent("extra", nil)
+		team, err := NewAIEvo(WithTeam([]schema.Agent{ping, pong, extra}), WithTeamLeader(ping),
+			WithCheckpointStore(store))
+		require.NoError(t, err)
+		return team, calls
+	}
+
+	team, _ := build(true)
+	require.NoError(t, team.Leave(context.Background(), "extra"))
+	_, err := team.Run(context.Background(), "start")
+	assert.ErrorIs(t, err, failed)
+
+	resumed, calls := build(false)
+	data, err := resumed.Checkpoints.Load(context.Background())
+	require.NoError(t, err)
+	result, err := resumed.Resume(context.Background(), bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, "done", result)
+	assert.Equal(t, 1, *calls)
+	assert.Nil(t, resumed.Agent("extra"))
+	assert.Equal(t, 6, resumed.Turn())
+	assert.Len(t, resumed.Memory.Load(context.Background(), nil), 6)
+}
+
+// flakyAgent fails when fail returns an error.
+type flakyAgent struct {
+	*scriptAgent
+	fail func() error
+}
+
+func (a *flakyAgent) Run(ctx context.Context, messages []schema.Message,
+	opts ...llm.GenerateOption) (*schema.Generation, error) {
+	if err := a.fail(); err != nil {
+		return nil, err
+	}
+	return a.scriptAgent.Run(ctx, messages, opts...)
+}
+
+func TestFork(t *testing.T) {
+	boss := newScriptAgent("boss", func(messages []schema.Message) []schema.Message {
+		// a team change without any is not kept in memory
+		return append([]schema.Message{{Type: schema.MsgTypeCreative, Sender: "boss"}},
+			send("writer", last(messages).Content)...)
+	})
+	writer := newScriptAgent("writer", func(messages []schema.Message) []schema.Message {
+		return end("draft on " + last(messages).Content)
+	})
+	var log bytes.Buffer
+	team, err := NewAIEvo(WithTeam([]schema.Agent{boss, writer}), WithTeamLeader(boss),
+		WithCallback(callback.NewRecorder(&log)))
+	require.NoError(t, err)
+	result, err := team.RunWithResult(context.Background(), "go")
+	require.NoError(t, err)
+	require.Len(t, result.Transcript, 3)
+	events, err := transcript.Load(&log)
+	require.NoError(t, err)
+	assert.Equal(t, result.Transcript, transcript.Messages(events))
+
+	session, err := team.Fork(context.Background(), result.Transcript, 1)
+	require.NoError(t, err)
+	content, err := session.Continue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "draft on go", content)
+
+	session, err = team.Fork(context.Background(), result.Transcript, 1, WithEditedMessage(
+		schema.Message{Sender: "boss", Receiver: "writer", Content: "rust"}))
+	require.NoError(t, err)
+	content, err = session.Continue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "draft on rust", content)
+	memory := session.Memory(context.Background())
+	assert.Equal(t, []string{"go", "rust", "draft on rust"},
+		[]string{memory[0].Content, memory[1].Content, memory[2].Content})
+
+	_, err = team.Fork(context.Background(), result.Transcript, 4)
+	assert.ErrorIs(t, err, ErrForkIndex)
+}
+
+// rememberAgent is a scriptAgent with a long-term memory.
+type rememberAgent struct {
+	*scriptAgent
+	memory *longterm.Memory
+}
+
+func (a *rememberAgent) LongTermMemory() *longterm.Memory {
+	return a.memory
+}
+
+func TestLongTermMemory(t *testing.T) {
+	ctx := context.Background()
+	store, err := longterm.NewFileStore(filepath.Join(t.TempDir(), "longterm.jsonl"))
+	require.NoError(t, err)
+	defer store.Close()
+	embedder := longterm.EmbedderFunc(func(_ context.Context, texts []string) ([][]float32, error) {
+		vectors := make([][]float32, len(texts))
+		for i := range vectors {
+			vectors[i] = []float32{1}
+		}
+		return vectors, nil
+	})
+	memory, err := longterm.NewMemory(&fakeLLM{content: "- the customer is Acme"}, embedder, store)
+	require.NoError(t, err)
+
+	triage := &rememberAgent{newScriptAgent("triage", func(messages []schema.Message) []schema.Message {
+		return end("solved")
+	}), memory}
+	team, err := NewAIEvo(WithTeam([]schema.Agent{triage}), WithTeamLeader(triage))
+	require.NoError(t, err)
+	_, err = team.Run(ctx, "billing is down")
+	require.NoError(t, err)
+
+	docs, err := memory.Recall(ctx, "triage", "who is the customer?")
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.Equal(t, "the customer is Acme", docs[0].PageContent)
+
+	// a member failing to remember does not fail the run
+	broken, err := longterm.NewMemory(&fakeLLM{content: "- the customer is Acme"},
+		longterm.EmbedderFunc(func(context.Context, []string) ([][]float32, error) {
+			return nil, errors.New("embedder down")
+		}), store)
+	require.NoError(t, err)
+	var events bytes.Buffer
+	forgetful := &rememberAgent{triage.scriptAgent, broken}
+	team, err = NewAIEvo(WithTeam([]schema.Agent{forgetful}),
+		WithTeamLeader(forgetful), WithCallback(callback.NewRecorder(&events)))
+	require.NoError(t, err)
+	content, err := team.Run(ctx, "billing is down")
+	require.NoError(t, err)
+	assert.Equal(t, "solved", content)
+	assert.Contains(t, events.String(), "embedder down")
+}
+
+func ballot(choice string) []schema.Message {
+	return end(fmt.Sprintf(`{"choice": %q, "reason": "I prefer it"}`, choice))
+}
+
+func TestPoll(t *testing.T) {
+	ctx := context.Background()
+	judge := newScriptAgent("judge", func(messages []schema.Message) []schema.Message {
+		return end(last(messages).Content)
+	})
+	fixed := func(name, choice string) *scriptAgent {
+		return newScriptAgent(name, func([]schema.Message) []schema.Message { return ballot(choice) })
+	}
+	// carol answers with a ballot not matching the schema first
+	carol := newScriptAgent("carol", func(messages []schema.Message) []schema.Message {
+		if strings.HasPrefix(last(messages).Content, "your ballot") {
+			return ballot("sushi")
+		}
+		return end("sushi, definitely")
+	})
+	// dave votes tacos, or pizza when tacos is out
+	dave := newScriptAgent("dave", func(messages []schema.Message) []schema.Message {
+		if strings.Contains(last(messages).Content, "tacos") {
+			return ballot("tacos")
+		}
+		return ballot("pizza")
+	})
+	var events bytes.Buffer
+	team, err := NewAIEvo(
+		WithTeam([]schema.Agent{judge, fixed("alice", "pizza"), fixed("bob", "pizza"), carol, dave,
+			fixed("erin", "sushi")}),
+		WithTeamLeader(judge),
+		WithCallback(callback.NewRecorder(&events)))
+	require.NoError(t, err)
+
+	result, err := team.Poll(ctx, Poll{
+		Question:    "What do we order?",
+		Options:     []string{"pizza", "sushi", "tacos"},
+		Coordinator: "judge",
+		Rule:        MajorityRunoff{},
+		Retries:     1,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "pizza", result.Winner)
+	require.Len(t, result.Rounds, 2)
+	assert.Equal(t, map[string]int{"pizza": 2, "sushi": 2, "tacos": 1}, result.Rounds[0].Counts)
+	assert.Equal(t, map[string]int{"pizza": 3, "sushi": 2}, result.Rounds[1].Counts)
+	require.Len(t, result.Ballots, 5)
+	assert.Equal(t, "carol", result.Ballots[2].Voter)
+	assert.Equal(t, "sushi", result.Ballots[2].Choice)
+	assert.False(t, result.Ballots[2].Void())
+
+	// the result is posted to the coordinator, and to the callbacks
+	messages := team.Memory.Load(ctx, nil)
+	require.NotEmpty(t, messages)
+	posted := last(messages)
+	assert.Equal(t, "judge", posted.Receiver)
+	assert.Contains(t, posted.Content, "Result: pizza (majority with runoff)")
+	assert.Contains(t, events.String(), `"type":"poll"`)
+
+	_, err = team.Poll(ctx, Poll{Question: "Yes?", Options: []string{"yes"}})
+	assert.ErrorIs(t, err, ErrInvalidPoll)
+	_, err = team.Poll(ctx, Poll{Question: "Yes?", Options: []string{"yes", "no"}, Retries: -1})
+	assert.ErrorIs(t, err, ErrInvalidPoll)
+
+	// a ballot never matching the schema is void
+	result, err = team.Poll(ctx, Poll{
+		Question: "What do we order?",
+		Options:  []string{"pizza", "sushi"},
+		Voters:   []schema.Agent{carol},
+		Rule:     Unanimity{},
+	})
+	require.NoError(t, err)
+	assert.False(t, result.Decided())
+	assert.True(t, result.Ballots[0].Void())
+
+	// the ballot is the final answer, not a message sent before it
+	chatty := newScriptAgent("chatty", func([]schema.Message) []schema.Message {
+		return append(send("judge", "let me think"), ballot("sushi")...)
+	})
+	result, err = team.Poll(ctx, Poll{
+		Question: "What do we order?",
+		Options:  []string{"pizza", "sushi"},
+		Voters:   []schema.Agent{chatty},
+		Rule:     Unanimity{},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "sushi", result.Winner)
+}
+
+func TestTallyRules(t *testing.T) {
+	options := []string{"A", "B", "C"}
+	choices := func(choices ...string) []schema.Ballot {
+		ballots := make([]schema.Ballot, 0, len(choices))
+		for _, choice := range choices {
+			ballots = append(ballots, schema.Ballot{Choice: choice})
+		}
+		return ballots
+	}
+
+	assert.Equal(t, "A", Plurality{}.Tally(options, choices("A", "A", "B")).Winner)
+	assert.Empty(t, Plurality{}.Tally(options, choices("A", "B", "C")).Winner)
+
+	tally := MajorityRunoff{}.Tally(options, choices("A", "A", "B", "C", "C"))
+	assert.Empty(t, tally.Winner)
+	assert.Equal(t, []string{"A", "C"}, tally.Runoff)
+	tally = MajorityRunoff{}.Tally(options, choices("A", "A", "A", "B", "C"))
+	assert.Equal(t, "A", tally.Winner)
+	// the options tied for the second place are all in the runoff
+	assert.Equal(t, options, MajorityRunoff{}.Tally(options, choices("A", "A", "B", "C")).Runoff)
+
+	tally = RankedChoice{}.Tally(options, []schema.Ballot{
+		{Ranking: []string{"A", "B", "C"}},
+		{Ranking: []string{"A", "C", "B"}},
+		{Ranking: []string{"B", "C", "A"}},
+		{Ranking: []string{"B", "A", "C"}},
+		{Ranking: []string{"C", "B", "A"}},
+	})
+	assert.Equal(t, "B", tally.Winner)
+	require.Len(t, tally.Rounds, 2)
+	assert.Equal(t, []string{"C"}, tally.Rounds[0].Eliminated)
+	assert.Equal(t, map[string]int{"A": 2, "B": 3}, tally.Rounds[1].Counts)
+
+	assert.Equal(t, "B", Unanimity{}.Tally(options, choices("B", "B")).Winner)
+	void := append(choices("B", "B"), schema.Ballot{Error: "no ballot"})
+	assert.Empty(t, Unanimity{}.Tally(options, void).Winner)
+	assert.Equal(t, "B", Plurality{}.Tally(options, void).Winner)
+}
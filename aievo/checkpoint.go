@@ -0,0 +1,154 @@
+package aievo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/antgroup/aievo/environment"
+	"github.com/antgroup/aievo/llm"
+	"github.com/antgroup/aievo/schema"
+)
+
+const _checkpointVersion = 1
+
+var ErrCheckpointVersion = errors.New("unsupported checkpoint version")
+
+// checkpoint is the serialized state of a run.
+type checkpoint struct {
+	Version int                `json:"version"`
+	Env     *environment.State `json:"env"`
+	// Agents are the states of the schema.StatefulAgent members
+	Agents map[string]json.RawMessage `json:"agents,omitempty"`
+	// Start is the number of messages before the run, for the long-term
+	// memories to remember the whole run once resumed
+	Start int `json:"start,omitempty"`
+}
+
+// CheckpointStore keeps the last checkpoint of a run.
+type CheckpointStore interface {
+	Save(ctx context.Context, checkpoint []byte) error
+	Load(ctx context.Context) ([]byte, error)
+}
+
+// FileCheckpointStore keeps the checkpoint in a file, replaced atomically.
+type FileCheckpointStore struct {
+	Path string
+}
+
+func NewFileCheckpointStore(path string) *FileCheckpointStore {
+	return &FileCheckpointStore{Path: path}
+}
+
+func (s *FileCheckpointStore) Save(_ context.Context, checkpoint []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.Path), filepath.Base(s.Path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(checkpoint); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.Path)
+}
+
+func (s *FileCheckpointStore) Load(_ context.Context) ([]byte, error) {
+	return os.ReadFile(s.Path)
+}
+
+// Checkpoint writes the state of the run to w: the memory with its
+// consumption cursor, the turn and token counters, the SOP, the members and
+// the state of the schema.StatefulAgent members, e.g. the progress of the
+// graph agents. It must be called between turns, when no agent runs. The
+// steps of a turn in flight are not kept, a resumed run replays the whole
+// turn, calling its LLM and tools again.
+func (e *AIEvo) Checkpoint(ctx context.Context, w io.Writer) error {
+	ctx = schema.ContextWithEnv(ctx, e.Environment)
+	state, err := e.Snapshot(ctx)
+	if err != nil {
+		return err
+	}
+	cp := &checkpoint{Version: _checkpointVersion, Env: state,
+		Agents: make(map[string]json.RawMessage)}
+	if start := runStartFrom(ctx); start != nil {
+		cp.Start = start.offset
+	}
+	for _, member := range e.GetTeam() {
+		sa, ok := member.(schema.StatefulAgent)
+		if !ok {
+			continue
+		}
+		data, err := sa.SaveState(ctx)
+		if err != nil {
+			return fmt.Errorf("agent %s: %w", member.Name(), err)
+		}
+		if data != nil {
+			cp.Agents[member.Name()] = data
+		}
+	}
+	return json.NewEncoder(w).Encode(cp)
+}
+
+// Resume restores the run checkpointed in r and continues it to the final
+// answer. The team must be built as for the run checkpointed, the turn in
+// progress when the checkpoint was taken is run again.
+func (e *AIEvo) Resume(ctx context.Context, r io.Reader,
+	opts ...llm.GenerateOption) (string, error) {
+	cp := &checkpoint{}
+	if err := json.NewDecoder(r).Decode(cp); err != nil {
+		return "", err
+	}
+	if cp.Version != _checkpointVersion || cp.Env == nil {
+		return "", fmt.Errorf("%w: %d", ErrCheckpointVersion, cp.Version)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	restore := func(ctx context.Context, _ string, _ ...llm.GenerateOption) (string, error) {
+		if err := e.Restore(ctx, cp.Env); err != nil {
+			return "", err
+		}
+		if start := runStartFrom(ctx); start != nil {
+			start.offset = cp.Start
+		}
+		for name, data := range cp.Agents {
+			sa, ok := e.Agent(name).(schema.StatefulAgent)
+			if !ok {
+				continue
+			}
+			if err := sa.LoadState(ctx, data); err != nil {
+				return "", fmt.Errorf("agent %s: %w", name, err)
+			}
+		}
+		return "", nil
+	}
+	return e.memorize(schema.ContextWithEnv(ctx, e.Environment),
+		Chain(restore, e.BuildPlan, e.Watch, func(ctx context.Context, _ string,
+			opts ...llm.GenerateOption) (string, error) {
+			return e.schedule(ctx, opts...)
+		}), "", opts...)
+}
+
+// autoCheckpoint saves a checkpoint to the store of e, if any.
+func (e *AIEvo) autoCheckpoint(ctx context.Context) error {
+	if e.Checkpoints == nil {
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := e.Checkpoint(ctx, &buf); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	if err := e.Checkpoints.Save(ctx, buf.Bytes()); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package aievo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/antgroup/aievo/schema"
+)
+
+var ErrForkIndex = errors.New("fork index out of range")
+
+// Fork starts a session from the messages of a run, e.g. the transcript of
+// RunWithResult, the memory of a session or the messages of an event log.
+// The first k messages are kept as handled, and the message k, replaced by
+// the one of WithEditedMessage if any, is the next one dispatched by
+// Session.Continue. The team is the one of e, e.g. with a fixed prompt.
+func (e *AIEvo) Fork(ctx context.Context, history []schema.Message, k int,
+	opts ...SessionOption) (*Session, error) {
+	if k < 0 || k > len(history) {
+		return nil, fmt.Errorf("%w: %d of %d messages", ErrForkIndex, k, len(history))
+	}
+	o := &sessionOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	messages := append([]schema.Message{}, history[:k]...)
+	switch {
+	case o.edited != nil:
+		edited := *o.edited
+		if edited.Type == "" {
+			edited.Type = schema.MsgTypeMsg
+		}
+		// the receivers may have changed
+		edited.AllReceiver = nil
+		messages = append(messages, edited)
+	case k < len(history):
+		messages = append(messages, history[k])
+	}
+
+	s := e.newSession(o)
+	if err := s.evo.Team.InitSubRelation(); err != nil {
+		return nil, err
+	}
+	if err := s.evo.Rewind(ctx, messages, k); err != nil {
+		return nil, err
+	}
+	s.started = true
+	return s, nil
+}
@@ -0,0 +1,69 @@
+package aievo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/antgroup/aievo/callback"
+	"github.com/antgroup/aievo/llm"
+	"github.com/antgroup/aievo/memory/longterm"
+	"github.com/antgroup/aievo/schema"
+)
+
+type runStartKey struct{}
+
+// runStart is where the run starts in the memory, the start of the run
+// resumed on Resume.
+type runStart struct {
+	offset int
+}
+
+func runStartFrom(ctx context.Context) *runStart {
+	start, _ := ctx.Value(runStartKey{}).(*runStart)
+	return start
+}
+
+// memorize runs handler, tells the messages kept in memory to the callback,
+// then stores the facts of the run in the long-term memories of the members
+// with one, each from the messages it saw. A member failing to remember is
+// told to the callback, the run is not failed.
+func (e *AIEvo) memorize(ctx context.Context, handler Handler, prompt string,
+	opts ...llm.GenerateOption) (string, error) {
+	start := &runStart{offset: len(e.history(ctx))}
+	ctx = context.WithValue(ctx, runStartKey{}, start)
+	owners := make([]schema.Agent, 0)
+	for _, member := range e.GetTeam() {
+		if owner, ok := member.(longterm.Owner); ok && owner.LongTermMemory() != nil {
+			owners = append(owners, member)
+		}
+	}
+
+	content, err := handler(ctx, prompt, opts...)
+	messages := e.history(ctx)
+	if hh, ok := e.Callback.(callback.HistoryHandler); ok {
+		hh.HandleHistory(ctx, messages)
+	}
+	if len(owners) == 0 || err != nil {
+		return content, err
+	}
+	offset := start.offset
+	if offset > len(messages) {
+		offset = 0
+	}
+	for _, owner := range owners {
+		seen := make([]schema.Message, 0)
+		for _, msg := range messages[offset:] {
+			if msg, ok := e.View(ctx, owner, msg); ok {
+				seen = append(seen, msg)
+			}
+		}
+		err = owner.(longterm.Owner).LongTermMemory().Remember(ctx, owner.Name(), seen)
+		if err == nil {
+			continue
+		}
+		if eh, ok := e.Callback.(callback.ErrorHandler); ok {
+			eh.HandleError(ctx, fmt.Errorf("long-term memory of %s: %w", owner.Name(), err))
+		}
+	}
+	return content, nil
+}
@@ -2,16 +2,21 @@ package aievo
 
 import (
 	"errors"
+	"time"
 
 	"github.com/antgroup/aievo/callback"
 	"github.com/antgroup/aievo/environment"
 	"github.com/antgroup/aievo/llm"
 	"github.com/antgroup/aievo/schema"
+	"github.com/antgroup/aievo/tool"
 )
 
 var (
 	ErrMissingLeader = errors.New("leader agent is not set")
 	ErrMissTeam      = errors.New("team is not set")
+	ErrInvalidResult = errors.New("invalid result")
+	ErrTurnTimeout   = errors.New("agent turn timed out")
+	ErrInterrupted   = errors.New("agent turn interrupted")
 )
 
 const (
@@ -34,6 +39,15 @@ type options struct {
 	planner        schema.Agent
 	watcher        schema.Agent
 	watchCondition func(message schema.Message) bool
+	resultSchema   *tool.PropertiesSchema
+	parallel       int
+	turnTimeout    time.Duration
+	scheduling     SchedulingStrategy
+	termination    []TerminationCondition
+	checkpoints    CheckpointStore
+	visibility     environment.VisibilityPolicy
+	strategies     map[string]environment.Strategy
+	msgTypes       []schema.MsgType
 
 	sop string
 }
@@ -103,6 +117,27 @@ func WithConditionSubscribe(subscribed schema.Agent, condition string,
 	}
 }
 
+// WithPredicateSubscribe makes subscribers receive the messages sent by
+// subscribed for which predicate holds, the messages of any sender when
+// subscribed is nil. See environment.CompileCondition for predicates
+// written as expressions.
+func WithPredicateSubscribe(subscribed schema.Agent, predicate func(message schema.Message) bool,
+	subscribers ...schema.Agent) Option {
+	return func(opts *options) {
+		for _, subscriber := range subscribers {
+			if subscriber == subscribed {
+				continue
+			}
+			opts.subscribes = append(opts.subscribes,
+				schema.Subscribe{
+					Subscribed: subscribed,
+					Subscriber: subscriber,
+					Predicate:  predicate,
+				})
+		}
+	}
+}
+
 func WithMaxTurn(maxTurn int) Option {
 	return func(opts *options) {
 		opts.maxTurn = maxTurn
@@ -145,3 +180,85 @@ func WithWatcher(agent schema.Agent, condition func(message schema.Message) bool
 		opts.watchCondition = condition
 	}
 }
+
+// WithResultSchema requires the final answer of the team to be a json
+// matching schema. A final answer violating it is sent back to its sender
+// with the violations.
+func WithResultSchema(schema *tool.PropertiesSchema) Option {
+	return func(opts *options) {
+		opts.resultSchema = schema
+	}
+}
+
+// WithParallelDispatch runs up to limit receivers of a message, e.g. sent
+// to ALL, concurrently. The receivers are expected to be independent: they
+// all see the memory as it was before the message was dispatched. The
+// callback handler must be safe for concurrent use.
+func WithParallelDispatch(limit int) Option {
+	return func(opts *options) {
+		opts.parallel = limit
+	}
+}
+
+// WithTurnTimeout limits the time of each run of an agent, the run fails
+// with ErrTurnTimeout when an agent takes longer.
+func WithTurnTimeout(timeout time.Duration) Option {
+	return func(opts *options) {
+		opts.turnTimeout = timeout
+	}
+}
+
+// WithSchedulingStrategy sets how the team chooses who speaks next, e.g.
+// RoundRobin for a debate. By default, the receivers of each message run.
+func WithSchedulingStrategy(strategy SchedulingStrategy) Option {
+	return func(opts *options) {
+		opts.scheduling = strategy
+	}
+}
+
+// WithTermination stops the runs as soon as any of conds is met, besides the
+// final answer and the max turn and token. The run returns the content of
+// the last message, and RunWithResult tells which condition was met.
+func WithTermination(conds ...TerminationCondition) Option {
+	return func(opts *options) {
+		opts.termination = append(opts.termination, conds...)
+	}
+}
+
+// WithCheckpointStore saves a checkpoint of the runs to store after each
+// turn, to Resume them after a failure. The memory must be a
+// schema.SnapshotMemory, e.g. the buffer memory. Each checkpoint holds the
+// whole memory, so the cost of the checkpoints of a run grows with the
+// square of its length.
+func WithCheckpointStore(store CheckpointStore) Option {
+	return func(opts *options) {
+		opts.checkpoints = store
+	}
+}
+
+// WithVisibilityPolicy sets what the agents see of the memory, e.g. an
+// environment.NewVisibility with the roles of a game.
+func WithVisibilityPolicy(policy environment.VisibilityPolicy) Option {
+	return func(opts *options) {
+		opts.visibility = policy
+	}
+}
+
+// WithStrategy sets the strategy of the messages of msgType, see
+// environment.Environment.RegisterStrategy.
+func WithStrategy(msgType string, strategy environment.Strategy) Option {
+	return func(opts *options) {
+		if opts.strategies == nil {
+			opts.strategies = make(map[string]environment.Strategy)
+		}
+		opts.strategies[msgType] = strategy
+	}
+}
+
+// WithMsgTypes registers custom message types in the environment, see
+// environment.Environment.RegisterMsgType.
+func WithMsgTypes(types ...schema.MsgType) Option {
+	return func(opts *options) {
+		opts.msgTypes = append(opts.msgTypes, types...)
+	}
+}
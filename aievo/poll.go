@@ -0,0 +1,261 @@
+package aievo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/antgroup/aievo/callback"
+	"github.com/antgroup/aievo/llm"
+	"github.com/antgroup/aievo/schema"
+	"github.com/antgroup/aievo/tool"
+	ujson "github.com/antgroup/aievo/utils/json"
+)
+
+const _pollSender = "Poll"
+
+const _pollQuestion = `%s
+
+%s
+Answer with the cate end, the content being your ballot, a json matching the schema: %s`
+
+var ErrInvalidPoll = errors.New("invalid poll")
+
+// Poll is a question put to the vote of agents.
+type Poll struct {
+	Question string
+	Options  []string
+	// Voters are the agents asked, the members of the team but the
+	// coordinator by default. They vote in parallel, each one seeing its
+	// memory and the question
+	Voters []schema.Agent
+	// Coordinator poses the question and is sent the result, the user by
+	// default
+	Coordinator string
+	// Rule tallies the ballots, Plurality by default
+	Rule TallyRule
+	// Fields are asked with each ballot besides the choice and the reason,
+	// e.g. a confidence
+	Fields map[string]tool.PropertySchema
+	// Retries is the number of times a voter is asked again for a ballot
+	// not matching the schema, before the ballot is void. It cannot be
+	// negative
+	Retries int
+}
+
+// Poll runs poll: the voters answer with a ballot, validated against the
+// schema of the poll, which the rule tallies. The result is sent to the
+// coordinator as a public message, and to the callbacks implementing
+// callback.PollHandler. A rule asking for a runoff makes the voters vote
+// again between the options of the runoff.
+func (e *AIEvo) Poll(ctx context.Context, poll Poll, opts ...llm.GenerateOption) (*schema.PollResult, error) {
+	ctx = schema.ContextWithEnv(ctx, e.Environment)
+	if poll.Coordinator == "" {
+		poll.Coordinator = _defaultSender
+	}
+	if poll.Rule == nil {
+		poll.Rule = Plurality{}
+	}
+	if poll.Voters == nil {
+		for _, member := range e.GetTeam() {
+			if !strings.EqualFold(member.Name(), poll.Coordinator) {
+				poll.Voters = append(poll.Voters, member)
+			}
+		}
+	}
+	if len(poll.Options) < 2 {
+		return nil, fmt.Errorf("%w: %d options", ErrInvalidPoll, len(poll.Options))
+	}
+	if len(poll.Voters) == 0 {
+		return nil, fmt.Errorf("%w: no voters", ErrInvalidPoll)
+	}
+	if poll.Retries < 0 {
+		return nil, fmt.Errorf("%w: %d retries", ErrInvalidPoll, poll.Retries)
+	}
+
+	result := &schema.PollResult{
+		Question:    poll.Question,
+		Coordinator: poll.Coordinator,
+		Rule:        poll.Rule.Name(),
+	}
+	options := poll.Options
+	for {
+		ballots, err := e.vote(ctx, &poll, options, opts...)
+		if err != nil {
+			return nil, err
+		}
+		tally := poll.Rule.Tally(options, ballots)
+		result.Ballots = ballots
+		result.Rounds = append(result.Rounds, tally.Rounds...)
+		result.Winner = tally.Winner
+		// a runoff between as many options would not end
+		if tally.Winner != "" || len(tally.Runoff) < 2 || len(tally.Runoff) >= len(options) {
+			break
+		}
+		options = tally.Runoff
+	}
+
+	receiver := poll.Coordinator
+	if e.Agent(receiver) == nil {
+		receiver = schema.MsgAllReceiver
+	}
+	err := e.Produce(ctx, schema.Message{
+		Type:       schema.MsgTypeMsg,
+		Content:    schema.ConvertPollResult(result),
+		Sender:     _pollSender,
+		Receiver:   receiver,
+		Visibility: schema.VisibilityPublic,
+		Metadata:   map[string]any{"poll": result.Question, "winner": result.Winner},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("post the poll result: %w", err)
+	}
+	if ph, ok := e.Callback.(callback.PollHandler); ok {
+		ph.HandlePollResult(ctx, result)
+	}
+	return result, nil
+}
+
+// vote asks the voters for their ballots between options, in parallel.
+func (e *AIEvo) vote(ctx context.Context, poll *Poll, options []string,
+	opts ...llm.GenerateOption) ([]schema.Ballot, error) {
+	ballotSchema := poll.ballotSchema(options)
+	bytes, _ := json.Marshal(ballotSchema)
+	instruction := "Choose one of the options: " + strings.Join(options, ", ") + "."
+	if poll.Rule.Ranked() {
+		instruction = "Rank the options, the preferred first: " + strings.Join(options, ", ") + "."
+	}
+	question := fmt.Sprintf(_pollQuestion, poll.Question, instruction, string(bytes))
+
+	var wg sync.WaitGroup
+	parallel := len(poll.Voters)
+	if e.ParallelDispatch > 0 {
+		parallel = e.ParallelDispatch
+	}
+	limit := make(chan struct{}, parallel)
+	ballots := make([]schema.Ballot, len(poll.Voters))
+	for i, voter := range poll.Voters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case limit <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-limit }()
+			ballots[i] = e.ballot(ctx, poll, voter, question, ballotSchema, opts...)
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ballots, nil
+}
+
+// ballot asks voter for its ballot, again with the violations while it
+// does not match the schema.
+func (e *AIEvo) ballot(ctx context.Context, poll *Poll, voter schema.Agent, question string,
+	ballotSchema *tool.PropertiesSchema, opts ...llm.GenerateOption) schema.Ballot {
+	messages := append(e.LoadMemory(ctx, voter), schema.Message{
+		Type:     schema.MsgTypeMsg,
+		Content:  question,
+		Sender:   poll.Coordinator,
+		Receiver: voter.Name(),
+	})
+	var err error
+	for attempt := 0; attempt <= poll.Retries; attempt++ {
+		var gen *schema.Generation
+		gen, err = e.runAgent(ctx, voter, messages, opts...)
+		if err != nil {
+			break
+		}
+		if len(gen.Messages) == 0 {
+			err = errors.New("no ballot")
+			break
+		}
+		answer := ballotMessage(gen.Messages)
+		content := ujson.RepairJsonString(answer.Content)
+		if err = ballotSchema.Validate([]byte(content)); err == nil {
+			return parseBallot(voter.Name(), content, poll.Fields)
+		}
+		messages = append(messages, answer, schema.Message{
+			Type:     schema.MsgTypeMsg,
+			Content:  fmt.Sprintf("your ballot does not match the schema:\n%s\nanswer again", err.Error()),
+			Sender:   poll.Coordinator,
+			Receiver: voter.Name(),
+		})
+	}
+	return schema.Ballot{Voter: voter.Name(), Error: err.Error()}
+}
+
+// ballotMessage returns the final answer of a voter, else its last message,
+// e.g. of a team whose first messages are between its members.
+func ballotMessage(messages []schema.Message) schema.Message {
+	for _, msg := range messages {
+		if msg.IsEnd() {
+			return msg
+		}
+	}
+	return messages[len(messages)-1]
+}
+
+func (p *Poll) ballotSchema(options []string) *tool.PropertiesSchema {
+	s := &tool.PropertiesSchema{
+		Type: tool.TypeJson,
+		Properties: map[string]tool.PropertySchema{
+			"reason": {Type: tool.TypeString, Description: "why you vote so, in one sentence"},
+		},
+	}
+	if p.Rule.Ranked() {
+		s.Properties["ranking"] = tool.PropertySchema{
+			Type:        tool.TypeArr,
+			Description: "the options ranked, the preferred first",
+			Items:       &tool.PropertySchema{Type: tool.TypeString, Enum: options},
+		}
+		s.Required = []string{"ranking"}
+	} else {
+		s.Properties["choice"] = tool.PropertySchema{
+			Type:        tool.TypeString,
+			Description: "the option chosen",
+			Enum:        options,
+		}
+		s.Required = []string{"choice"}
+	}
+	names := make([]string, 0, len(p.Fields))
+	for name, field := range p.Fields {
+		s.Properties[name] = field
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	s.Required = append(s.Required, names...)
+	return s
+}
+
+func parseBallot(voter, content string, fields map[string]tool.PropertySchema) schema.Ballot {
+	ballot := schema.Ballot{}
+	values := make(map[string]any)
+	_ = json.Unmarshal([]byte(content), &ballot)
+	_ = json.Unmarshal([]byte(content), &values)
+	ballot.Voter, ballot.Error, ballot.Fields = voter, "", nil
+	for name := range fields {
+		if ballot.Fields == nil {
+			ballot.Fields = make(map[string]any, len(fields))
+		}
+		ballot.Fields[name] = values[name]
+	}
+	// an option ranked twice counts once, at its best rank
+	ranking := ballot.Ranking[:0]
+	for _, option := range ballot.Ranking {
+		if !slices.Contains(ranking, option) {
+			ranking = append(ranking, option)
+		}
+	}
+	ballot.Ranking = ranking
+	return ballot
+}
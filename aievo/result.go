@@ -0,0 +1,130 @@
+package aievo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/antgroup/aievo/environment"
+	"github.com/antgroup/aievo/llm"
+	"github.com/antgroup/aievo/schema"
+)
+
+var (
+	ErrMaxTurnsExceeded  = environment.ErrMaxTurnsExceeded
+	ErrMaxTokensExceeded = environment.ErrMaxTokensExceeded
+	ErrNoFinalAnswer     = errors.New("no final answer")
+	ErrTerminated        = errors.New("terminated")
+)
+
+type TerminationReason string
+
+const (
+	// ReasonEnd means an agent sent the final answer
+	ReasonEnd       TerminationReason = "end"
+	ReasonMaxTurns  TerminationReason = "max_turns"
+	ReasonMaxTokens TerminationReason = "max_tokens"
+	// ReasonNoMessage means no message was left to dispatch before the
+	// final answer was sent
+	ReasonNoMessage TerminationReason = "no_message"
+	ReasonError     TerminationReason = "error"
+	ReasonCancelled TerminationReason = "cancelled"
+	// ReasonTerminated means a termination condition was met
+	ReasonTerminated TerminationReason = "terminated"
+)
+
+// RunResult describes how a run went.
+type RunResult struct {
+	Content string
+	Reason  TerminationReason
+	// Err is nil when Reason is ReasonEnd
+	Err   error
+	Turns int
+	// Tokens is the number of tokens consumed, TokensByAgent splits them
+	// by the sender of the messages
+	Tokens        int
+	TokensByAgent map[string]int
+	// Transcript is the messages produced during the run, all of them when
+	// the memory is a schema.SnapshotMemory, e.g. a window one
+	Transcript []schema.Message
+	SOP        string
+	Elapsed    time.Duration
+}
+
+type runStateKey struct{}
+
+// runState is filled by the scheduler during a run.
+type runState struct {
+	ended bool
+	// terminated is why the termination condition was met
+	terminated string
+}
+
+func runStateFrom(ctx context.Context) *runState {
+	state, _ := ctx.Value(runStateKey{}).(*runState)
+	return state
+}
+
+// history returns the messages of the memory, not windowed when it is a
+// schema.SnapshotMemory.
+func (e *AIEvo) history(ctx context.Context) []schema.Message {
+	if sm, ok := e.Memory.(schema.SnapshotMemory); ok {
+		if messages, _, err := sm.Snapshot(ctx); err == nil {
+			return messages
+		}
+	}
+	return e.Memory.Load(ctx, nil)
+}
+
+// RunWithResult is like Run, but tells why the run stopped. The error
+// returned is the one of the result, e.g. ErrMaxTurnsExceeded when the max
+// turn is reached before the final answer. The turns and tokens are the
+// ones of this run, the counters of e going on from the previous runs.
+func (e *AIEvo) RunWithResult(ctx context.Context, prompt string,
+	opts ...llm.GenerateOption) (*RunResult, error) {
+	start := time.Now()
+	offset := len(e.history(ctx))
+	turn, token := e.Turn(), e.Token()
+	state := &runState{}
+	content, err := e.Run(context.WithValue(ctx, runStateKey{}, state), prompt, opts...)
+
+	result := &RunResult{
+		Content:       content,
+		Turns:         e.Turn() - turn,
+		Tokens:        e.Token() - token,
+		TokensByAgent: make(map[string]int),
+		SOP:           e.SOP(),
+	}
+	messages := e.history(ctx)
+	if offset <= len(messages) {
+		result.Transcript = messages[offset:]
+	}
+	for _, msg := range result.Transcript {
+		result.TokensByAgent[msg.Sender] += msg.Token
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		result.Reason, result.Err = ReasonCancelled, ctx.Err()
+	case err != nil:
+		result.Reason, result.Err = ReasonError, err
+	case state.ended:
+		result.Reason = ReasonEnd
+	case state.terminated != "":
+		result.Reason = ReasonTerminated
+		result.Err = fmt.Errorf("%w: %s", ErrTerminated, state.terminated)
+	default:
+		result.Err = e.Exceeded()
+		switch {
+		case errors.Is(result.Err, ErrMaxTurnsExceeded):
+			result.Reason = ReasonMaxTurns
+		case errors.Is(result.Err, ErrMaxTokensExceeded):
+			result.Reason = ReasonMaxTokens
+		default:
+			result.Reason, result.Err = ReasonNoMessage, ErrNoFinalAnswer
+		}
+	}
+	result.Elapsed = time.Since(start)
+	return result, result.Err
+}
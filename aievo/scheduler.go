@@ -2,10 +2,15 @@ package aievo
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
+	"github.com/antgroup/aievo/feedback"
 	"github.com/antgroup/aievo/llm"
 	"github.com/antgroup/aievo/schema"
+	"github.com/antgroup/aievo/utils/json"
 )
 
 func (e *AIEvo) BuildPlan(_ context.Context, _ string, _ ...llm.GenerateOption) (string, error) {
@@ -37,21 +42,28 @@ func (e *AIEvo) BuildSOP(ctx context.Context, prompt string, opts ...llm.Generat
 func (e *AIEvo) Watch(ctx context.Context, _ string, opts ...llm.GenerateOption) (string, error) {
 	// 开启一个 watcher 观察所有的执行流程，并给出评判建议，用于剔除和更新agent
 	if e.Watcher != nil {
-		e.WatchChan = make(chan schema.Message)
-		e.WatchChanDone = make(chan struct{})
+		watch, done := make(chan schema.Message), make(chan struct{})
+		e.WatchChan, e.WatchChanDone = watch, done
 		go func() {
-			for message := range e.WatchChan {
-				if e.WatchCondition != nil && !e.WatchCondition(message) {
-					e.WatchChanDone <- struct{}{}
-					continue
+			for {
+				var message schema.Message
+				select {
+				case <-ctx.Done():
+					return
+				case message = <-watch:
 				}
-				generation, err := e.Watcher.Run(ctx,
-					e.LoadMemory(ctx, e.Watcher), opts...)
-				e.WatchChanDone <- struct{}{}
-				if err != nil {
-					continue
+				if e.WatchCondition == nil || e.WatchCondition(message) {
+					generation, err := e.Watcher.Run(ctx,
+						e.LoadMemory(ctx, e.Watcher), opts...)
+					if err == nil {
+						_ = e.Produce(ctx, generation.Messages...)
+					}
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case done <- struct{}{}:
 				}
-				_ = e.Produce(ctx, generation.Messages...)
 			}
 		}()
 	}
@@ -65,49 +77,204 @@ func (e *AIEvo) Scheduler(ctx context.Context, prompt string, opts ...llm.Genera
 		Sender:   _defaultSender,
 		Receiver: e.GetTeamLeader().Name(),
 	})
-	for msg := e.Consume(ctx); msg != nil; msg = e.Consume(ctx) {
+	return e.schedule(ctx, opts...)
+}
+
+// schedule dispatches the messages until the final answer.
+func (e *AIEvo) schedule(ctx context.Context, opts ...llm.GenerateOption) (string, error) {
+	var invalid error
+	termination := &TerminationState{Start: time.Now()}
+	for {
+		// taken before the message is consumed, so a run resumed from it
+		// runs the turn again
+		if err := e.autoCheckpoint(ctx); err != nil {
+			return "", err
+		}
+		msg := e.Consume(ctx)
+		if msg == nil {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
 		if msg.IsEnd() {
-			return msg.Content, nil
+			content, err := e.checkResult(ctx, msg)
+			if err == nil {
+				if state := runStateFrom(ctx); state != nil {
+					state.ended = true
+				}
+				return content, nil
+			}
+			invalid = err
+			continue
 		}
-		receivers := msg.Receivers()
-		for _, rec := range receivers {
-			receiver := e.Agent(rec)
-			if receiver == nil {
-				if len(receivers) == 1 {
-					return msg.Content, fmt.Errorf(
-						"get unexpected agent %s", msg.Receiver)
+		if e.Termination != nil {
+			termination.Messages = append(termination.Messages, *msg)
+			if reason, ok := e.Termination.Terminate(termination); ok {
+				if state := runStateFrom(ctx); state != nil {
+					state.terminated = reason
 				}
-				continue
+				return msg.Content, nil
 			}
-			messages := e.LoadMemory(ctx, receiver)
-			if e.Callback != nil {
-				e.Callback.HandleAgentStart(ctx, receiver, messages)
+		}
+		agents, err := e.scheduling().Next(ctx, e.Environment, msg)
+		if err != nil {
+			return msg.Content, err
+		}
+		// an interrupted turn is dropped, the run goes on with the
+		// message of the interrupt
+		if e.ParallelDispatch > 1 && len(agents) > 1 {
+			err := e.dispatchParallel(ctx, agents, opts...)
+			if err != nil && !errors.Is(err, ErrInterrupted) {
+				return "", err
+			}
+			continue
+		}
+		for _, receiver := range agents {
+			gen, err := e.runAgent(ctx, receiver, e.LoadMemory(ctx, receiver), opts...)
+			if errors.Is(err, ErrInterrupted) {
+				break
 			}
-			gen, err := receiver.Run(ctx, messages, opts...)
 			if err != nil {
 				return "", err
 			}
-			if e.Callback != nil {
-				e.Callback.HandleAgentEnd(ctx, receiver, gen)
-			}
+			_ = e.Produce(ctx, gen.Messages...)
+			e.broadcast(ctx, gen.Messages...)
+		}
+	}
+	if invalid != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidResult, invalid)
+	}
+	return "", nil
+}
+
+func (e *AIEvo) scheduling() SchedulingStrategy {
+	if e.Scheduling == nil {
+		return MessageDriven{}
+	}
+	return e.Scheduling
+}
 
-			if gen.Messages == nil {
-				return "", fmt.Errorf("generating messages is nil for agent %s", msg.Receiver)
+// dispatchParallel runs the receivers of a message concurrently, each one
+// seeing the memory as it was before any of them ran. The messages produced
+// are merged in the order of the receivers.
+func (e *AIEvo) dispatchParallel(ctx context.Context, agents []schema.Agent,
+	opts ...llm.GenerateOption) error {
+	memories := make([][]schema.Message, len(agents))
+	for i, receiver := range agents {
+		memories[i] = e.LoadMemory(ctx, receiver)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg    sync.WaitGroup
+		once  sync.Once
+		first error
+	)
+	limit := make(chan struct{}, e.ParallelDispatch)
+	gens := make([]*schema.Generation, len(agents))
+	for i, receiver := range agents {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			if ctx.Err() != nil {
+				return
 			}
+			gen, err := e.runAgent(ctx, receiver, memories[i], opts...)
+			if err != nil {
+				once.Do(func() {
+					first = err
+					cancel()
+				})
+				return
+			}
+			gens[i] = gen
+		}()
+	}
+	wg.Wait()
+	if first != nil {
+		return first
+	}
 
-			_ = e.Produce(ctx, gen.Messages...)
-			e.broadcast(gen.Messages...)
+	for _, gen := range gens {
+		_ = e.Produce(ctx, gen.Messages...)
+		e.broadcast(ctx, gen.Messages...)
+	}
+	return nil
+}
+
+func (e *AIEvo) runAgent(ctx context.Context, receiver schema.Agent,
+	messages []schema.Message, opts ...llm.GenerateOption) (*schema.Generation, error) {
+	if e.Callback != nil {
+		e.Callback.HandleAgentStart(ctx, receiver, messages)
+	}
+	runCtx, end := e.startTurn(ctx)
+	defer end()
+	if e.TurnTimeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(runCtx, e.TurnTimeout)
+		defer cancel()
+	}
+	gen, err := receiver.Run(runCtx, messages, opts...)
+	// the agent may finish right as the turn times out
+	if err != nil && ctx.Err() == nil && runCtx.Err() != nil {
+		if errors.Is(context.Cause(runCtx), ErrInterrupted) {
+			return nil, fmt.Errorf("%w: agent %s", ErrInterrupted, receiver.Name())
 		}
+		return nil, fmt.Errorf("%w: agent %s", ErrTurnTimeout, receiver.Name())
 	}
-	return "", nil
+	if err != nil {
+		return nil, err
+	}
+	if e.Callback != nil {
+		e.Callback.HandleAgentEnd(ctx, receiver, gen)
+	}
+	if gen.Messages == nil {
+		return nil, fmt.Errorf("generating messages is nil for agent %s", receiver.Name())
+	}
+	return gen, nil
+}
+
+// checkResult validates the final answer against the result schema. When it
+// does not match, the violations are sent back to the sender to fix it.
+func (e *AIEvo) checkResult(ctx context.Context, msg *schema.Message) (string, error) {
+	if e.ResultSchema == nil {
+		return msg.Content, nil
+	}
+	content := json.RepairJsonString(msg.Content)
+	err := e.ResultSchema.Validate([]byte(content))
+	if err == nil {
+		return content, nil
+	}
+	if e.Agent(msg.Sender) == nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidResult, err)
+	}
+	_ = e.Produce(ctx, schema.Message{
+		Type:     schema.MsgTypeMsg,
+		Content:  feedback.SchemaViolation(e.ResultSchema, err),
+		Sender:   _defaultSender,
+		Receiver: msg.Sender,
+	})
+	return "", err
 }
 
-func (e *AIEvo) broadcast(messages ...schema.Message) {
+func (e *AIEvo) broadcast(ctx context.Context, messages ...schema.Message) {
 	if e.WatchChan == nil {
 		return
 	}
 	for _, message := range messages {
-		e.WatchChan <- message
-		<-e.WatchChanDone
+		select {
+		case <-ctx.Done():
+			return
+		case e.WatchChan <- message:
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-e.WatchChanDone:
+		}
 	}
 }
@@ -1,10 +1,33 @@
 package aievo
 
 import (
+	"context"
+	"sync"
+	"time"
+
 	"github.com/antgroup/aievo/environment"
+	"github.com/antgroup/aievo/tool"
 )
 
 type AIEvo struct {
 	Handler Handler
+	// ResultSchema is the schema the final answer must match, if set
+	ResultSchema *tool.PropertiesSchema
+	// ParallelDispatch is the max number of receivers of a message run
+	// concurrently, receivers are run one by one when it is not above 1
+	ParallelDispatch int
+	// TurnTimeout limits the time of each run of an agent, if set
+	TurnTimeout time.Duration
+	// Scheduling chooses who speaks next, MessageDriven by default
+	Scheduling SchedulingStrategy
+	// Termination stops the runs early when it is met, if set
+	Termination TerminationCondition
+	// Checkpoints keeps a checkpoint of the runs after each turn, if set
+	Checkpoints CheckpointStore
 	*environment.Environment
+
+	// cancels of the agent turns running, for the interrupts
+	turnMu  sync.Mutex
+	turnID  int
+	cancels map[int]context.CancelCauseFunc
 }
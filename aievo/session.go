@@ -0,0 +1,142 @@
+package aievo
+
+import (
+	"context"
+	"sync"
+
+	"github.com/antgroup/aievo/callback"
+	"github.com/antgroup/aievo/llm"
+	"github.com/antgroup/aievo/schema"
+)
+
+type sessionOptions struct {
+	memory   schema.Memory
+	callback callback.Handler
+	edited   *schema.Message
+}
+
+type SessionOption func(*sessionOptions)
+
+// WithSessionMemory sets the memory of the session, a new buffer memory is
+// used by default.
+func WithSessionMemory(memory schema.Memory) SessionOption {
+	return func(opts *sessionOptions) {
+		opts.memory = memory
+	}
+}
+
+// WithSessionCallback sets the callback of the session instead of the one
+// of the team.
+func WithSessionCallback(handler callback.Handler) SessionOption {
+	return func(opts *sessionOptions) {
+		opts.callback = handler
+	}
+}
+
+// WithEditedMessage replaces the message a forked session starts with.
+func WithEditedMessage(msg schema.Message) SessionOption {
+	return func(opts *sessionOptions) {
+		opts.edited = &msg
+	}
+}
+
+// Session is a conversation with a team. Each session has its own
+// environment, memory and counters, so sessions of the same team can run
+// in parallel, while the agents are shared.
+type Session struct {
+	evo     *AIEvo
+	started bool
+	tokens  int
+
+	mu sync.Mutex
+}
+
+// NewSession starts a new conversation with the team. The team itself is
+// used as a blueprint and is not changed by the session.
+func (e *AIEvo) NewSession(opts ...SessionOption) *Session {
+	o := &sessionOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return e.newSession(o)
+}
+
+func (e *AIEvo) newSession(o *sessionOptions) *Session {
+	env := e.Environment.Clone(o.memory)
+	if o.callback != nil {
+		env.Callback = o.callback
+	}
+	evo := &AIEvo{
+		ResultSchema:     e.ResultSchema,
+		ParallelDispatch: e.ParallelDispatch,
+		TurnTimeout:      e.TurnTimeout,
+		Scheduling:       e.Scheduling,
+		Termination:      e.Termination,
+		Environment:      env,
+	}
+	evo.Handler = Chain(evo.BuildPlan, evo.BuildSOP, evo.Watch, evo.Scheduler)
+	return &Session{evo: evo}
+}
+
+// Send sends prompt to the leader of the team and returns the final answer.
+// The team sees the messages of the previous sends, and the turn and token
+// limits apply to each send.
+func (s *Session) Send(ctx context.Context, prompt string,
+	opts ...llm.GenerateOption) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	handler := Chain(s.evo.Watch, s.evo.Scheduler)
+	if !s.started {
+		handler = s.evo.Handler
+		s.started = true
+	}
+	return s.run(ctx, handler, prompt, opts...)
+}
+
+// Continue dispatches the messages left, e.g. of a forked session, until
+// the final answer, without sending a new prompt.
+func (s *Session) Continue(ctx context.Context, opts ...llm.GenerateOption) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.started = true
+	return s.run(ctx, Chain(s.evo.BuildPlan, s.evo.Watch, func(ctx context.Context, _ string,
+		opts ...llm.GenerateOption) (string, error) {
+		return s.evo.schedule(ctx, opts...)
+	}), "", opts...)
+}
+
+func (s *Session) run(ctx context.Context, handler Handler, prompt string,
+	opts ...llm.GenerateOption) (string, error) {
+	s.evo.ResetCounters()
+	// stops the watcher when the send ends
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	content, err := s.evo.memorize(schema.ContextWithEnv(ctx, s.evo.Environment),
+		handler, prompt, opts...)
+	s.tokens += s.evo.Token()
+	return content, err
+}
+
+// Inject sends msg to the team while a send runs, ahead of the queued
+// messages. Between sends, msg is handled first by the next send.
+func (s *Session) Inject(ctx context.Context, msg schema.Message) error {
+	return s.evo.Inject(ctx, msg)
+}
+
+// Interrupt cancels the agent turns in flight and sends msg, by default to
+// the leader, e.g. to correct the course of the team.
+func (s *Session) Interrupt(ctx context.Context, msg schema.Message) error {
+	return s.evo.Interrupt(ctx, msg)
+}
+
+// Memory returns the messages of the conversation so far.
+func (s *Session) Memory(ctx context.Context) []schema.Message {
+	return s.evo.Memory.Load(ctx, nil)
+}
+
+// Tokens returns the number of tokens consumed by all the sends.
+func (s *Session) Tokens() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tokens
+}
@@ -0,0 +1,58 @@
+package aievo
+
+import (
+	"context"
+
+	"github.com/antgroup/aievo/schema"
+)
+
+// Inject sends msg to a running team ahead of the queued messages, so it is
+// the next one dispatched. The sender defaults to the user and the receiver
+// to the leader.
+func (e *AIEvo) Inject(ctx context.Context, msg schema.Message) error {
+	if msg.Type == "" {
+		msg.Type = schema.MsgTypeMsg
+	}
+	if msg.Sender == "" {
+		msg.Sender = _defaultSender
+	}
+	if msg.Receiver == "" {
+		msg.Receiver = e.GetTeamLeader().Name()
+	}
+	return e.Environment.Inject(ctx, msg)
+}
+
+// Interrupt injects msg, by default a correction to the leader, and cancels
+// the agent turns in flight, whose output is dropped. The run goes on with
+// msg.
+func (e *AIEvo) Interrupt(ctx context.Context, msg schema.Message) error {
+	if err := e.Inject(ctx, msg); err != nil {
+		return err
+	}
+	e.turnMu.Lock()
+	defer e.turnMu.Unlock()
+	for _, cancel := range e.cancels {
+		cancel(ErrInterrupted)
+	}
+	return nil
+}
+
+// startTurn returns the context of an agent turn, which Interrupt cancels,
+// and the func to call when the turn is over.
+func (e *AIEvo) startTurn(ctx context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	e.turnMu.Lock()
+	defer e.turnMu.Unlock()
+	if e.cancels == nil {
+		e.cancels = make(map[int]context.CancelCauseFunc)
+	}
+	e.turnID++
+	id := e.turnID
+	e.cancels[id] = cancel
+	return ctx, func() {
+		e.turnMu.Lock()
+		delete(e.cancels, id)
+		e.turnMu.Unlock()
+		cancel(nil)
+	}
+}
@@ -0,0 +1,230 @@
+package aievo
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/antgroup/aievo/environment"
+	"github.com/antgroup/aievo/llm"
+	"github.com/antgroup/aievo/prompt"
+	"github.com/antgroup/aievo/schema"
+)
+
+const _defaultManagerWindow = 20
+
+const _defaultManagerPrompt = `You are the manager of a group chat. Choose who speaks next to move the conversation towards the goal.
+
+Participants:
+{{.participants}}
+
+Conversation:
+~~~
+{{.conversation}}
+~~~
+
+The last speaker is {{.sender}}. Answer only with the name of the next speaker, one of: {{.names}}.
+`
+
+// SchedulingStrategy chooses the agents to run for each message consumed.
+// Strategies choosing other agents than the receivers of the message are
+// meant for teams whose members see all the messages, e.g. with
+// environment.ALLSubMode.
+type SchedulingStrategy interface {
+	Next(ctx context.Context, env *environment.Environment, msg *schema.Message) ([]schema.Agent, error)
+}
+
+// MessageDriven runs the receivers of the message, the agent sending a
+// message chooses who speaks next.
+type MessageDriven struct{}
+
+func (MessageDriven) Next(_ context.Context, env *environment.Environment,
+	msg *schema.Message) ([]schema.Agent, error) {
+	receivers := msg.Receivers()
+	agents := make([]schema.Agent, 0, len(receivers))
+	for _, rec := range receivers {
+		receiver := env.Agent(rec)
+		if receiver == nil {
+			if len(receivers) == 1 {
+				return nil, fmt.Errorf(
+					"get unexpected agent %s", msg.Receiver)
+			}
+			continue
+		}
+		agents = append(agents, receiver)
+	}
+	return agents, nil
+}
+
+// RoundRobin makes the members speak in turn, in the order of the team,
+// starting with the leader.
+type RoundRobin struct{}
+
+func (RoundRobin) Next(_ context.Context, env *environment.Environment,
+	msg *schema.Message) ([]schema.Agent, error) {
+	members := env.GetTeam()
+	for i, member := range members {
+		if member.Name() == msg.Sender {
+			return []schema.Agent{members[(i+1)%len(members)]}, nil
+		}
+	}
+	return []schema.Agent{env.GetTeamLeader()}, nil
+}
+
+// Bidder is implemented by the agents able to tell how relevant they are to
+// speak next, given the messages they see.
+type Bidder interface {
+	Bid(ctx context.Context, messages []schema.Message) (float64, error)
+}
+
+// Bidding asks the members other than the sender for a bid, and runs the
+// highest bidder. Members which are not a Bidder or fail do not bid. When
+// no bid is above zero, the receivers of the message are run.
+type Bidding struct{}
+
+func (Bidding) Next(ctx context.Context, env *environment.Environment,
+	msg *schema.Message) ([]schema.Agent, error) {
+	var (
+		best  schema.Agent
+		score float64
+	)
+	for _, member := range env.GetTeam() {
+		bidder, ok := member.(Bidder)
+		if !ok || member.Name() == msg.Sender {
+			continue
+		}
+		bid, err := bidder.Bid(ctx, env.LoadMemory(ctx, member))
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			continue
+		}
+		if bid > score {
+			best, score = member, bid
+		}
+	}
+	if best == nil {
+		return MessageDriven{}.Next(ctx, env, msg)
+	}
+	return []schema.Agent{best}, nil
+}
+
+// Manager lets an LLM read the conversation and the descriptions of the
+// members, and choose who speaks next, as the manager of a group chat.
+// When the answer is not a member, the receivers of the message are run.
+type Manager struct {
+	llm    llm.LLM
+	tpl    *prompt.PromptTemplate
+	window int
+}
+
+type ManagerOption func(m *Manager)
+
+// WithManagerPrompt sets the prompt of the manager, which can use
+// participants, conversation, sender and names.
+func WithManagerPrompt(template string) ManagerOption {
+	return func(m *Manager) {
+		m.tpl, _ = prompt.NewPromptTemplate(template)
+	}
+}
+
+// WithManagerWindow sets the number of the last messages the manager
+// reads, 20 by default.
+func WithManagerWindow(window int) ManagerOption {
+	return func(m *Manager) {
+		m.window = window
+	}
+}
+
+func NewManager(LLM llm.LLM, opts ...ManagerOption) (*Manager, error) {
+	if LLM == nil {
+		return nil, schema.ErrMissingLLM
+	}
+	tpl, err := prompt.NewPromptTemplate(_defaultManagerPrompt)
+	if err != nil {
+		return nil, err
+	}
+	m := &Manager{llm: LLM, tpl: tpl, window: _defaultManagerWindow}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.tpl == nil {
+		return nil, schema.ErrParsePromptTemplate
+	}
+	return m, nil
+}
+
+func (m *Manager) Next(ctx context.Context, env *environment.Environment,
+	msg *schema.Message) ([]schema.Agent, error) {
+	members := env.GetTeam()
+	names := make([]string, 0, len(members))
+	var participants strings.Builder
+	for _, member := range members {
+		names = append(names, member.Name())
+		participants.WriteString(fmt.Sprintf("%s: %s\n", member.Name(), member.Description()))
+	}
+	messages := env.Memory.Load(ctx, nil)
+	if m.window > 0 && len(messages) > m.window {
+		messages = messages[len(messages)-m.window:]
+	}
+	var conversation strings.Builder
+	for _, message := range messages {
+		if message.Content == "" {
+			continue
+		}
+		conversation.WriteString(fmt.Sprintf("%s: %s\n", message.Sender, message.Content))
+	}
+
+	p, err := m.tpl.Format(map[string]any{
+		"participants": strings.TrimSpace(participants.String()),
+		"conversation": strings.TrimSpace(conversation.String()),
+		"sender":       msg.Sender,
+		"names":        strings.Join(names, ", "),
+	})
+	if err != nil {
+		return nil, err
+	}
+	output, err := m.llm.Generate(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	if speaker := matchSpeaker(output.Content, members); speaker != nil {
+		return []schema.Agent{speaker}, nil
+	}
+	return MessageDriven{}.Next(ctx, env, msg)
+}
+
+// matchSpeaker finds the member named by answer, or else the first member
+// it mentions as a word.
+func matchSpeaker(answer string, members []schema.Agent) schema.Agent {
+	answer = strings.Trim(strings.TrimSpace(answer), "\"'`.*")
+	for _, member := range members {
+		if strings.EqualFold(member.Name(), answer) {
+			return member
+		}
+	}
+	var (
+		speaker schema.Agent
+		first   = -1
+	)
+	for _, member := range members {
+		re, err := regexp.Compile(`(?i)\b` + regexp.QuoteMeta(member.Name()) + `\b`)
+		if err != nil {
+			continue
+		}
+		loc := re.FindStringIndex(answer)
+		if loc != nil && (first < 0 || loc[0] < first) {
+			speaker, first = member, loc[0]
+		}
+	}
+	return speaker
+}
+
+var (
+	_ SchedulingStrategy = MessageDriven{}
+	_ SchedulingStrategy = RoundRobin{}
+	_ SchedulingStrategy = Bidding{}
+	_ SchedulingStrategy = (*Manager)(nil)
+)
@@ -0,0 +1,205 @@
+package aievo
+
+import (
+	"slices"
+
+	"github.com/antgroup/aievo/schema"
+)
+
+// TallyRule decides the winner of a poll from its ballots. The void
+// ballots are not counted.
+type TallyRule interface {
+	Name() string
+	// Ranked reports whether the voters rank the options instead of
+	// choosing one
+	Ranked() bool
+	Tally(options []string, ballots []schema.Ballot) Tally
+}
+
+// Tally is the count of the ballots of a vote.
+type Tally struct {
+	// Winner is empty when the ballots do not decide
+	Winner string
+	Rounds []schema.PollRound
+	// Runoff are the options of a new vote, when the ballots do not decide
+	Runoff []string
+}
+
+// Plurality elects the option chosen by the most voters. A tie for the
+// first place decides nothing.
+type Plurality struct{}
+
+func (Plurality) Name() string { return "plurality" }
+
+func (Plurality) Ranked() bool { return false }
+
+func (Plurality) Tally(options []string, ballots []schema.Ballot) Tally {
+	round := countChoices(options, ballots)
+	first := leaders(options, round.Counts)
+	tally := Tally{Rounds: []schema.PollRound{round}}
+	if len(first) == 1 {
+		tally.Winner = first[0]
+	}
+	return tally
+}
+
+// MajorityRunoff elects the option chosen by more than half of the ballots
+// counted, or else asks for a runoff between the two first options, the
+// ones tied with them included.
+type MajorityRunoff struct{}
+
+func (MajorityRunoff) Name() string { return "majority with runoff" }
+
+func (MajorityRunoff) Ranked() bool { return false }
+
+func (MajorityRunoff) Tally(options []string, ballots []schema.Ballot) Tally {
+	round := countChoices(options, ballots)
+	tally := Tally{Rounds: []schema.PollRound{round}}
+	first := leaders(options, round.Counts)
+	if len(first) == 1 && round.Counts[first[0]]*2 > counted(ballots) {
+		tally.Winner = first[0]
+		return tally
+	}
+	if len(first) >= 2 {
+		tally.Runoff = first
+		return tally
+	}
+	second := leaders(without(options, first), round.Counts)
+	tally.Runoff = append(first, second...)
+	return tally
+}
+
+// RankedChoice counts the ballots for their first option still running,
+// and eliminates the last options until one is ranked first by more than
+// half of the ballots counted, i.e. an instant runoff. The ballots ranking
+// no option still running are not counted.
+type RankedChoice struct{}
+
+func (RankedChoice) Name() string { return "ranked choice" }
+
+func (RankedChoice) Ranked() bool { return true }
+
+func (RankedChoice) Tally(options []string, ballots []schema.Ballot) Tally {
+	tally := Tally{}
+	running := options
+	for len(running) > 0 {
+		round := schema.PollRound{Options: running, Counts: zeroCounts(running)}
+		total := 0
+		for _, ballot := range ballots {
+			if ballot.Void() {
+				continue
+			}
+			for _, option := range ballot.Ranking {
+				if _, ok := round.Counts[option]; ok {
+					round.Counts[option]++
+					total++
+					break
+				}
+			}
+		}
+		first := leaders(running, round.Counts)
+		if len(first) == 1 && round.Counts[first[0]]*2 > total {
+			tally.Winner = first[0]
+			tally.Rounds = append(tally.Rounds, round)
+			return tally
+		}
+		last := trailers(running, round.Counts)
+		// the options left are all tied
+		if len(last) == len(running) {
+			tally.Rounds = append(tally.Rounds, round)
+			return tally
+		}
+		round.Eliminated = last
+		tally.Rounds = append(tally.Rounds, round)
+		running = without(running, last)
+	}
+	return tally
+}
+
+// Unanimity elects the option chosen by every voter. A void ballot
+// prevents it.
+type Unanimity struct{}
+
+func (Unanimity) Name() string { return "unanimity" }
+
+func (Unanimity) Ranked() bool { return false }
+
+func (Unanimity) Tally(options []string, ballots []schema.Ballot) Tally {
+	round := countChoices(options, ballots)
+	tally := Tally{Rounds: []schema.PollRound{round}}
+	for _, option := range options {
+		if len(ballots) > 0 && round.Counts[option] == len(ballots) {
+			tally.Winner = option
+		}
+	}
+	return tally
+}
+
+func countChoices(options []string, ballots []schema.Ballot) schema.PollRound {
+	round := schema.PollRound{Options: options, Counts: zeroCounts(options)}
+	for _, ballot := range ballots {
+		if _, ok := round.Counts[ballot.Choice]; ok && !ballot.Void() {
+			round.Counts[ballot.Choice]++
+		}
+	}
+	return round
+}
+
+func zeroCounts(options []string) map[string]int {
+	counts := make(map[string]int, len(options))
+	for _, option := range options {
+		counts[option] = 0
+	}
+	return counts
+}
+
+// counted is the number of ballots not void.
+func counted(ballots []schema.Ballot) int {
+	n := 0
+	for _, ballot := range ballots {
+		if !ballot.Void() {
+			n++
+		}
+	}
+	return n
+}
+
+// leaders are the options with the most votes, none without votes.
+func leaders(options []string, counts map[string]int) []string {
+	best := 1
+	result := make([]string, 0)
+	for _, option := range options {
+		switch {
+		case counts[option] > best:
+			best = counts[option]
+			result = append(result[:0], option)
+		case counts[option] == best:
+			result = append(result, option)
+		}
+	}
+	return result
+}
+
+// trailers are the options with the fewest votes.
+func trailers(options []string, counts map[string]int) []string {
+	result := make([]string, 0)
+	for _, option := range options {
+		switch {
+		case len(result) == 0 || counts[option] < counts[result[0]]:
+			result = append(result[:0], option)
+		case counts[option] == counts[result[0]]:
+			result = append(result, option)
+		}
+	}
+	return result
+}
+
+func without(options, removed []string) []string {
+	result := make([]string, 0, len(options))
+	for _, option := range options {
+		if !slices.Contains(removed, option) {
+			result = append(result, option)
+		}
+	}
+	return result
+}
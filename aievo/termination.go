@@ -0,0 +1,163 @@
+package aievo
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/antgroup/aievo/schema"
+)
+
+// TerminationState is what the termination conditions see of a run.
+type TerminationState struct {
+	Start time.Time
+	// Messages are the messages consumed so far, the last one is the
+	// message about to be dispatched
+	Messages []schema.Message
+}
+
+// TerminationCondition stops a run before the next message is dispatched,
+// returning why when it is met.
+type TerminationCondition interface {
+	Terminate(state *TerminationState) (string, bool)
+}
+
+type TerminationFunc func(state *TerminationState) (string, bool)
+
+func (f TerminationFunc) Terminate(state *TerminationState) (string, bool) {
+	return f(state)
+}
+
+// TextMention is met when the last message mentions text, e.g. "TERMINATE".
+func TextMention(text string) TerminationCondition {
+	return TerminationFunc(func(state *TerminationState) (string, bool) {
+		last := state.Messages[len(state.Messages)-1]
+		return fmt.Sprintf("%s mentioned %q", last.Sender, text),
+			strings.Contains(last.Content, text)
+	})
+}
+
+// Timeout is met when the run lasts more than timeout. It is checked
+// between the turns, use the context to stop a turn.
+func Timeout(timeout time.Duration) TerminationCondition {
+	return TerminationFunc(func(state *TerminationState) (string, bool) {
+		return fmt.Sprintf("timeout after %s", timeout), time.Since(state.Start) > timeout
+	})
+}
+
+// MaxConsecutiveTurns is met when an agent sent the last n messages.
+func MaxConsecutiveTurns(n int) TerminationCondition {
+	return TerminationFunc(func(state *TerminationState) (string, bool) {
+		messages := state.Messages
+		if len(messages) < n || n <= 0 {
+			return "", false
+		}
+		sender := messages[len(messages)-1].Sender
+		for _, msg := range messages[len(messages)-n:] {
+			if msg.Sender != sender {
+				return "", false
+			}
+		}
+		return fmt.Sprintf("%s took %d turns in a row", sender, n), true
+	})
+}
+
+// PingPong is met when two agents exchanged rounds times in a row, each
+// message being at least threshold similar, between 0 and 1, to the
+// previous message of its sender.
+func PingPong(rounds int, threshold float64) TerminationCondition {
+	return TerminationFunc(func(state *TerminationState) (string, bool) {
+		messages := state.Messages
+		if rounds <= 0 || len(messages) < 2*rounds {
+			return "", false
+		}
+		messages = messages[len(messages)-2*rounds:]
+		a, b := messages[0].Sender, messages[1].Sender
+		if a == b {
+			return "", false
+		}
+		for i, msg := range messages {
+			if msg.Sender != []string{a, b}[i%2] {
+				return "", false
+			}
+			if i >= 2 && similarity(msg.Content, messages[i-2].Content) < threshold {
+				return "", false
+			}
+		}
+		return fmt.Sprintf("%s and %s repeat each other", a, b), true
+	})
+}
+
+// NoProgress is met when each of the last window messages is at least
+// threshold similar, between 0 and 1, to an earlier message of the run.
+func NoProgress(window int, threshold float64) TerminationCondition {
+	return TerminationFunc(func(state *TerminationState) (string, bool) {
+		messages := state.Messages
+		if window <= 0 || len(messages) <= window {
+			return "", false
+		}
+		for i := len(messages) - window; i < len(messages); i++ {
+			repeated := false
+			for j := 0; j < i && !repeated; j++ {
+				repeated = similarity(messages[i].Content, messages[j].Content) >= threshold
+			}
+			if !repeated {
+				return "", false
+			}
+		}
+		return fmt.Sprintf("no progress in the last %d messages", window), true
+	})
+}
+
+// And is met when all the conditions are.
+func And(conds ...TerminationCondition) TerminationCondition {
+	return TerminationFunc(func(state *TerminationState) (string, bool) {
+		reasons := make([]string, 0, len(conds))
+		for _, cond := range conds {
+			reason, ok := cond.Terminate(state)
+			if !ok {
+				return "", false
+			}
+			reasons = append(reasons, reason)
+		}
+		return strings.Join(reasons, " and "), len(conds) != 0
+	})
+}
+
+// Or is met when any of the conditions is.
+func Or(conds ...TerminationCondition) TerminationCondition {
+	return TerminationFunc(func(state *TerminationState) (string, bool) {
+		for _, cond := range conds {
+			if reason, ok := cond.Terminate(state); ok {
+				return reason, true
+			}
+		}
+		return "", false
+	})
+}
+
+// similarity is the jaccard similarity of the words of a and b.
+func similarity(a, b string) float64 {
+	wa, wb := words(a), words(b)
+	if len(wa) == 0 && len(wb) == 0 {
+		return 1
+	}
+	common := 0
+	for w := range wa {
+		if wb[w] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(wa)+len(wb)-common)
+}
+
+func words(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		set[w] = true
+	}
+	return set
+}
@@ -0,0 +1,27 @@
+package aievo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/antgroup/aievo/llm"
+	ujson "github.com/antgroup/aievo/utils/json"
+)
+
+// RunTyped runs the team and decodes its final answer into T. It is best
+// used together with WithResultSchema, so that the answer is validated and
+// fixed by the team before being decoded. A run stopping without a final
+// answer returns the error of RunWithResult, e.g. ErrMaxTurnsExceeded.
+func RunTyped[T any](ctx context.Context, e *AIEvo, prompt string,
+	opts ...llm.GenerateOption) (T, error) {
+	var result T
+	run, err := e.RunWithResult(ctx, prompt, opts...)
+	if err != nil {
+		return result, err
+	}
+	if err = json.Unmarshal([]byte(ujson.RepairJsonString(run.Content)), &result); err != nil {
+		return result, fmt.Errorf("%w: %w", ErrInvalidResult, err)
+	}
+	return result, nil
+}
@@ -22,3 +22,51 @@ type Handler interface {
 	HandleStreamingFunc(ctx context.Context, chunk []byte) error
 	HandleReasoningStreamingFunc(ctx context.Context, chunk []byte) error
 }
+
+// TeamHandler is implemented by the handlers interested in the changes of
+// the members of a team at runtime.
+type TeamHandler interface {
+	HandleMemberJoin(ctx context.Context, a schema.Agent)
+	HandleMemberLeave(ctx context.Context, a schema.Agent)
+	HandleMemberReplace(ctx context.Context, old, new schema.Agent)
+}
+
+// FeedbackHandler is implemented by the handlers interested in the verdicts
+// of the feedbacks on the outputs of the agents.
+type FeedbackHandler interface {
+	HandleFeedback(ctx context.Context, agent string, verdict, msg string)
+}
+
+// MemoryHandler is implemented by the handlers interested in the messages
+// a memory leaves out of what it loads, e.g. to fit a token budget.
+type MemoryHandler interface {
+	HandleMemoryElided(ctx context.Context, elided []schema.Message)
+}
+
+// HistoryHandler is implemented by the handlers interested in the messages
+// kept in the memory of a team at the end of each of its runs, in their
+// order there, e.g. to fork the run later. A nested team does not tell
+// its own.
+type HistoryHandler interface {
+	HandleHistory(ctx context.Context, messages []schema.Message)
+}
+
+// BlackboardHandler is implemented by the handlers interested in the
+// writes to the blackboard of an environment. old is nil for a new key,
+// new is nil for a deleted one.
+type BlackboardHandler interface {
+	HandleBlackboardChange(ctx context.Context, old, new *schema.BlackboardEntry)
+}
+
+// ErrorHandler is implemented by the handlers interested in the errors a
+// run recovers from, e.g. a summary failing, the messages being then kept
+// whole.
+type ErrorHandler interface {
+	HandleError(ctx context.Context, err error)
+}
+
+// PollHandler is implemented by the handlers interested in the results of
+// the polls of a team.
+type PollHandler interface {
+	HandlePollResult(ctx context.Context, result *schema.PollResult)
+}
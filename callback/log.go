@@ -59,9 +59,17 @@ func (LogHandler) HandleMessageInQueue(ctx context.Context, message *schema.Mess
 	}
 	if message.IsMsg() {
 		fmt.Printf("(%s -> %s)(%s): %s\n", message.Sender, message.Receiver, message.Condition, message.Content)
+	} else if message.IsCustom() {
+		fmt.Printf("(%s -> %s)[%s]: %s\n", message.Sender, message.Receiver, message.Type, message.Content)
 	}
 }
 
+var _ ErrorHandler = LogHandler{}
+
+func (LogHandler) HandleError(ctx context.Context, err error) {
+	fmt.Println("Error:", err)
+}
+
 func (LogHandler) HandleMessageOutQueue(ctx context.Context, message *schema.Message) {
 }
 
@@ -0,0 +1,232 @@
+package callback
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/antgroup/aievo/llm"
+	"github.com/antgroup/aievo/schema"
+)
+
+type EventType string
+
+const (
+	EventSOP            EventType = "sop"
+	EventLLMStart       EventType = "llm_start"
+	EventLLMEnd         EventType = "llm_end"
+	EventAgentStart     EventType = "agent_start"
+	EventAgentEnd       EventType = "agent_end"
+	EventActionStart    EventType = "action_start"
+	EventActionEnd      EventType = "action_end"
+	EventRetrieverStart EventType = "retriever_start"
+	EventRetrieverEnd   EventType = "retriever_end"
+	EventMessageIn      EventType = "message_in"
+	EventMessageOut     EventType = "message_out"
+	EventFeedback       EventType = "feedback"
+	EventMemberJoin     EventType = "member_join"
+	EventMemberLeave    EventType = "member_leave"
+	EventMemberReplace  EventType = "member_replace"
+	EventMemoryElided   EventType = "memory_elided"
+	EventHistory        EventType = "history"
+	EventBlackboard     EventType = "blackboard"
+	EventPoll           EventType = "poll"
+	EventError          EventType = "error"
+)
+
+// Event is a line of the event log written by a Recorder. Only the fields
+// of its type are set.
+type Event struct {
+	Seq  int       `json:"seq"`
+	Time time.Time `json:"time"`
+	Type EventType `json:"type"`
+	// Agent is the agent the event is about, the new member for a replace
+	Agent     string             `json:"agent,omitempty"`
+	Old       string             `json:"old,omitempty"`
+	Message   *schema.Message    `json:"message,omitempty"`
+	Messages  []schema.Message   `json:"messages,omitempty"`
+	Action    *schema.StepAction `json:"action,omitempty"`
+	Prompt    string             `json:"prompt,omitempty"`
+	Output    string             `json:"output,omitempty"`
+	Reasoning string             `json:"reasoning,omitempty"`
+	Tokens    int                `json:"tokens,omitempty"`
+	SOP       string             `json:"sop,omitempty"`
+	Query     string             `json:"query,omitempty"`
+	Documents []schema.Document  `json:"documents,omitempty"`
+	Verdict   string             `json:"verdict,omitempty"`
+	Feedback  string             `json:"feedback,omitempty"`
+	// Entry is the blackboard entry written, OldEntry the one replaced
+	Entry    *schema.BlackboardEntry `json:"entry,omitempty"`
+	OldEntry *schema.BlackboardEntry `json:"old_entry,omitempty"`
+	Poll     *schema.PollResult      `json:"poll,omitempty"`
+	Error    string                  `json:"error,omitempty"`
+}
+
+// Recorder writes every event of a run to w as JSON lines, to be loaded by
+// the transcript package. It is safe for concurrent use. The streaming
+// chunks are not recorded, the LLM outputs are.
+type Recorder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	seq int
+	err error
+}
+
+var (
+	_ Handler           = (*Recorder)(nil)
+	_ TeamHandler       = (*Recorder)(nil)
+	_ FeedbackHandler   = (*Recorder)(nil)
+	_ MemoryHandler     = (*Recorder)(nil)
+	_ HistoryHandler    = (*Recorder)(nil)
+	_ BlackboardHandler = (*Recorder)(nil)
+	_ PollHandler       = (*Recorder)(nil)
+	_ ErrorHandler      = (*Recorder)(nil)
+)
+
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{enc: json.NewEncoder(w)}
+}
+
+// Err returns the first error writing the events, the events after it are
+// dropped.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+func (r *Recorder) record(event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	r.seq++
+	event.Seq, event.Time = r.seq, time.Now()
+	r.err = r.enc.Encode(event)
+}
+
+func (r *Recorder) HandleSOP(_ context.Context, sop string) {
+	r.record(Event{Type: EventSOP, SOP: sop})
+}
+
+func (r *Recorder) HandleLLMStart(_ context.Context, prompt string) {
+	r.record(Event{Type: EventLLMStart, Prompt: prompt})
+}
+
+func (r *Recorder) HandleLLMEnd(_ context.Context, output *llm.Generation) {
+	event := Event{Type: EventLLMEnd}
+	if output != nil {
+		event.Output, event.Reasoning = output.Content, output.ReasoningContent
+		if output.Usage != nil {
+			event.Tokens = output.Usage.TotalTokens
+		}
+	}
+	r.record(event)
+}
+
+func (r *Recorder) HandleAgentStart(_ context.Context, a schema.Agent, messages []schema.Message) {
+	r.record(Event{Type: EventAgentStart, Agent: agentName(a), Messages: messages})
+}
+
+func (r *Recorder) HandleAgentEnd(_ context.Context, a schema.Agent, result *schema.Generation) {
+	event := Event{Type: EventAgentEnd, Agent: agentName(a)}
+	if result != nil {
+		event.Messages, event.Tokens = result.Messages, result.TotalTokens
+	}
+	r.record(event)
+}
+
+func (r *Recorder) HandleAgentActionStart(_ context.Context, agent string, action *schema.StepAction) {
+	r.record(Event{Type: EventActionStart, Agent: agent, Action: copyAction(action)})
+}
+
+func (r *Recorder) HandleAgentActionEnd(_ context.Context, agent string, action *schema.StepAction) {
+	r.record(Event{Type: EventActionEnd, Agent: agent, Action: copyAction(action)})
+}
+
+func (r *Recorder) HandleRetrieverStart(_ context.Context, query string) {
+	r.record(Event{Type: EventRetrieverStart, Query: query})
+}
+
+func (r *Recorder) HandleRetrieverEnd(_ context.Context, query string, documents []schema.Document) {
+	r.record(Event{Type: EventRetrieverEnd, Query: query, Documents: documents})
+}
+
+func (r *Recorder) HandleMessageInQueue(_ context.Context, message *schema.Message) {
+	r.record(Event{Type: EventMessageIn, Message: copyMessage(message)})
+}
+
+func (r *Recorder) HandleMessageOutQueue(_ context.Context, message *schema.Message) {
+	r.record(Event{Type: EventMessageOut, Message: copyMessage(message)})
+}
+
+func (r *Recorder) HandleStreamingFunc(_ context.Context, _ []byte) error {
+	return nil
+}
+
+func (r *Recorder) HandleReasoningStreamingFunc(_ context.Context, _ []byte) error {
+	return nil
+}
+
+func (r *Recorder) HandleFeedback(_ context.Context, agent string, verdict, msg string) {
+	r.record(Event{Type: EventFeedback, Agent: agent, Verdict: verdict, Feedback: msg})
+}
+
+func (r *Recorder) HandleMemberJoin(_ context.Context, a schema.Agent) {
+	r.record(Event{Type: EventMemberJoin, Agent: agentName(a)})
+}
+
+func (r *Recorder) HandleMemberLeave(_ context.Context, a schema.Agent) {
+	r.record(Event{Type: EventMemberLeave, Agent: agentName(a)})
+}
+
+func (r *Recorder) HandleMemberReplace(_ context.Context, old, new schema.Agent) {
+	r.record(Event{Type: EventMemberReplace, Agent: agentName(new), Old: agentName(old)})
+}
+
+func (r *Recorder) HandleMemoryElided(_ context.Context, elided []schema.Message) {
+	r.record(Event{Type: EventMemoryElided, Messages: elided})
+}
+
+func (r *Recorder) HandleHistory(_ context.Context, messages []schema.Message) {
+	r.record(Event{Type: EventHistory, Messages: messages})
+}
+
+func (r *Recorder) HandleBlackboardChange(_ context.Context, old, new *schema.BlackboardEntry) {
+	r.record(Event{Type: EventBlackboard, Entry: new, OldEntry: old})
+}
+
+func (r *Recorder) HandlePollResult(_ context.Context, result *schema.PollResult) {
+	r.record(Event{Type: EventPoll, Poll: result})
+}
+
+func (r *Recorder) HandleError(_ context.Context, err error) {
+	r.record(Event{Type: EventError, Error: err.Error()})
+}
+
+func agentName(a schema.Agent) string {
+	if a == nil {
+		return ""
+	}
+	return a.Name()
+}
+
+// copyMessage copies message, which may change after the event.
+func copyMessage(message *schema.Message) *schema.Message {
+	if message == nil {
+		return nil
+	}
+	m := *message
+	return &m
+}
+
+func copyAction(action *schema.StepAction) *schema.StepAction {
+	if action == nil {
+		return nil
+	}
+	a := *action
+	return &a
+}
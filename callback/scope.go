@@ -0,0 +1,170 @@
+package callback
+
+import (
+	"context"
+
+	"github.com/antgroup/aievo/llm"
+	"github.com/antgroup/aievo/schema"
+)
+
+// ScopeHandler prefixes agent names with a scope before passing events to
+// the wrapped handler, so events from a nested team can be told apart
+// from the events of the outer team.
+type ScopeHandler struct {
+	Scope   string
+	Handler Handler
+}
+
+var _ Handler = (*ScopeHandler)(nil)
+
+func NewScopeHandler(scope string, handler Handler) *ScopeHandler {
+	return &ScopeHandler{Scope: scope, Handler: handler}
+}
+
+func (h *ScopeHandler) HandleSOP(ctx context.Context, sop string) {
+	h.Handler.HandleSOP(ctx, sop)
+}
+
+func (h *ScopeHandler) HandleLLMStart(ctx context.Context, prompt string) {
+	h.Handler.HandleLLMStart(ctx, prompt)
+}
+
+func (h *ScopeHandler) HandleLLMEnd(ctx context.Context, output *llm.Generation) {
+	h.Handler.HandleLLMEnd(ctx, output)
+}
+
+func (h *ScopeHandler) HandleAgentStart(ctx context.Context, a schema.Agent, messages []schema.Message) {
+	h.Handler.HandleAgentStart(ctx, h.agent(a), messages)
+}
+
+func (h *ScopeHandler) HandleAgentEnd(ctx context.Context, a schema.Agent, result *schema.Generation) {
+	h.Handler.HandleAgentEnd(ctx, h.agent(a), result)
+}
+
+func (h *ScopeHandler) HandleAgentActionStart(ctx context.Context, agent string, action *schema.StepAction) {
+	h.Handler.HandleAgentActionStart(ctx, h.name(agent), action)
+}
+
+func (h *ScopeHandler) HandleAgentActionEnd(ctx context.Context, agent string, action *schema.StepAction) {
+	h.Handler.HandleAgentActionEnd(ctx, h.name(agent), action)
+}
+
+func (h *ScopeHandler) HandleRetrieverStart(ctx context.Context, query string) {
+	h.Handler.HandleRetrieverStart(ctx, query)
+}
+
+func (h *ScopeHandler) HandleRetrieverEnd(ctx context.Context, query string, documents []schema.Document) {
+	h.Handler.HandleRetrieverEnd(ctx, query, documents)
+}
+
+func (h *ScopeHandler) HandleMessageInQueue(ctx context.Context, message *schema.Message) {
+	h.Handler.HandleMessageInQueue(ctx, h.message(message))
+}
+
+func (h *ScopeHandler) HandleMessageOutQueue(ctx context.Context, message *schema.Message) {
+	h.Handler.HandleMessageOutQueue(ctx, h.message(message))
+}
+
+func (h *ScopeHandler) HandleStreamingFunc(ctx context.Context, chunk []byte) error {
+	return h.Handler.HandleStreamingFunc(ctx, chunk)
+}
+
+func (h *ScopeHandler) HandleReasoningStreamingFunc(ctx context.Context, chunk []byte) error {
+	return h.Handler.HandleReasoningStreamingFunc(ctx, chunk)
+}
+
+func (h *ScopeHandler) name(name string) string {
+	if name == "" {
+		return h.Scope
+	}
+	return h.Scope + "/" + name
+}
+
+func (h *ScopeHandler) agent(a schema.Agent) schema.Agent {
+	if a == nil {
+		return nil
+	}
+	return &scopedAgent{Agent: a, name: h.name(a.Name())}
+}
+
+func (h *ScopeHandler) message(message *schema.Message) *schema.Message {
+	if message == nil {
+		return nil
+	}
+	scoped := *message
+	scoped.Sender = h.name(message.Sender)
+	if message.Receiver != "" {
+		scoped.Receiver = h.name(message.Receiver)
+	}
+	return &scoped
+}
+
+// scopedAgent reports a scoped name for the wrapped agent.
+type scopedAgent struct {
+	schema.Agent
+	name string
+}
+
+func (a *scopedAgent) Name() string {
+	return a.name
+}
+
+var _ TeamHandler = (*ScopeHandler)(nil)
+
+func (h *ScopeHandler) HandleMemberJoin(ctx context.Context, a schema.Agent) {
+	if th, ok := h.Handler.(TeamHandler); ok {
+		th.HandleMemberJoin(ctx, h.agent(a))
+	}
+}
+
+func (h *ScopeHandler) HandleMemberLeave(ctx context.Context, a schema.Agent) {
+	if th, ok := h.Handler.(TeamHandler); ok {
+		th.HandleMemberLeave(ctx, h.agent(a))
+	}
+}
+
+func (h *ScopeHandler) HandleMemberReplace(ctx context.Context, old, new schema.Agent) {
+	if th, ok := h.Handler.(TeamHandler); ok {
+		th.HandleMemberReplace(ctx, h.agent(old), h.agent(new))
+	}
+}
+
+var _ FeedbackHandler = (*ScopeHandler)(nil)
+
+func (h *ScopeHandler) HandleFeedback(ctx context.Context, agent string, verdict, msg string) {
+	if fh, ok := h.Handler.(FeedbackHandler); ok {
+		fh.HandleFeedback(ctx, h.name(agent), verdict, msg)
+	}
+}
+
+var _ MemoryHandler = (*ScopeHandler)(nil)
+
+func (h *ScopeHandler) HandleMemoryElided(ctx context.Context, elided []schema.Message) {
+	if mh, ok := h.Handler.(MemoryHandler); ok {
+		mh.HandleMemoryElided(ctx, elided)
+	}
+}
+
+var _ BlackboardHandler = (*ScopeHandler)(nil)
+
+func (h *ScopeHandler) HandleBlackboardChange(ctx context.Context, old, new *schema.BlackboardEntry) {
+	if bh, ok := h.Handler.(BlackboardHandler); ok {
+		bh.HandleBlackboardChange(ctx, old, new)
+	}
+}
+
+var _ ErrorHandler = (*ScopeHandler)(nil)
+
+func (h *ScopeHandler) HandleError(ctx context.Context, err error) {
+	if eh, ok := h.Handler.(ErrorHandler); ok {
+		eh.HandleError(ctx, err)
+	}
+}
+
+var _ PollHandler = (*ScopeHandler)(nil)
+
+func (h *ScopeHandler) HandlePollResult(ctx context.Context, result *schema.PollResult) {
+	if ph, ok := h.Handler.(PollHandler); ok {
+		ph.HandlePollResult(ctx, result)
+	}
+}
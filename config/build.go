@@ -0,0 +1,423 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/antgroup/aievo/agent"
+	"github.com/antgroup/aievo/aievo"
+	"github.com/antgroup/aievo/callback"
+	"github.com/antgroup/aievo/environment"
+	"github.com/antgroup/aievo/feedback"
+	"github.com/antgroup/aievo/llm"
+	"github.com/antgroup/aievo/llm/ollama"
+	"github.com/antgroup/aievo/llm/openai"
+	"github.com/antgroup/aievo/schema"
+	"github.com/antgroup/aievo/tool"
+	"github.com/antgroup/aievo/tool/mcp"
+)
+
+var subscribeModes = map[string]environment.SubscribeMode{
+	"":        environment.DefaultSubMode,
+	"default": environment.DefaultSubMode,
+	"leader":  environment.LeaderSubMode,
+	"all":     environment.ALLSubMode,
+	"custom":  environment.CustomSubMode,
+}
+
+type options struct {
+	callback  callback.Handler
+	tools     map[string]ToolFactory
+	llms      map[string]llm.LLM
+	teamOpts  []aievo.Option
+	agentOpts []agent.Option
+}
+
+type Option func(*options)
+
+// WithCallback sets the callback of the team and all its agents.
+func WithCallback(handler callback.Handler) Option {
+	return func(o *options) {
+		o.callback = handler
+	}
+}
+
+// WithToolFactory registers a tool only for this build.
+func WithToolFactory(name string, factory ToolFactory) Option {
+	return func(o *options) {
+		o.tools[name] = factory
+	}
+}
+
+// WithLLM provides the llm called name instead of building it from the
+// spec, the empty name is the default llm.
+func WithLLM(name string, l llm.LLM) Option {
+	return func(o *options) {
+		o.llms[name] = l
+	}
+}
+
+// WithTeamOptions appends options to the built team, they take precedence
+// over the spec.
+func WithTeamOptions(opts ...aievo.Option) Option {
+	return func(o *options) {
+		o.teamOpts = append(o.teamOpts, opts...)
+	}
+}
+
+// WithAgentOptions appends options to all the built agents.
+func WithAgentOptions(opts ...agent.Option) Option {
+	return func(o *options) {
+		o.agentOpts = append(o.agentOpts, opts...)
+	}
+}
+
+// Load reads the spec in path and builds the team.
+func Load(path string, opts ...Option) (*aievo.AIEvo, error) {
+	t, err := LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return t.Build(opts...)
+}
+
+// Build validates the spec and constructs the team it describes. Values
+// of llms and tool args are expanded with environment variables, so
+// secrets can be written as ${OPENAI_API_KEY}.
+func (t *Team) Build(opts ...Option) (*aievo.AIEvo, error) {
+	o := &options{
+		tools: make(map[string]ToolFactory),
+		llms:  make(map[string]llm.LLM),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if err := t.validate(o.llms); err != nil {
+		return nil, err
+	}
+
+	b := &builder{
+		team:    t,
+		options: o,
+		env:     environment.NewEnv(),
+		llms:    make(map[string]llm.LLM),
+		agents:  make(map[string]schema.Agent),
+	}
+	return b.build()
+}
+
+type builder struct {
+	team    *Team
+	options *options
+	env     *environment.Environment
+	llms    map[string]llm.LLM
+	mcp     map[string]tool.Tool
+	agents  map[string]schema.Agent
+}
+
+func (b *builder) build() (*aievo.AIEvo, error) {
+	if err := b.loadMCP(); err != nil {
+		return nil, fieldError("mcp_servers", "%s", err)
+	}
+	sop, err := b.text(b.team.SOP, b.team.SOPFile)
+	if err != nil {
+		return nil, fieldError("sop_file", "%s", err)
+	}
+	b.env.Sop = sop
+
+	mode := subscribeModes[strings.ToLower(b.team.SubscribeMode)]
+	teamOpts := []aievo.Option{
+		aievo.WithEnvironment(b.env),
+		aievo.WithSubScribeMode(mode),
+		aievo.WithCallback(b.options.callback),
+	}
+	if sop != "" {
+		teamOpts = append(teamOpts, aievo.WithSOP(sop))
+	}
+	if b.team.MaxTurn > 0 {
+		teamOpts = append(teamOpts, aievo.WithMaxTurn(b.team.MaxTurn))
+	}
+	if b.team.MaxToken > 0 {
+		teamOpts = append(teamOpts, aievo.WithMaxToken(b.team.MaxToken))
+	}
+
+	members := make([]schema.Agent, 0, len(b.team.Agents))
+	for i, spec := range b.team.Agents {
+		a, err := b.buildAgent(spec)
+		if err != nil {
+			return nil, fieldError(fmt.Sprintf("agents[%d]", i), "%s", err)
+		}
+		b.agents[strings.ToLower(spec.Name)] = a
+		switch spec.Type {
+		case AgentTypeSop:
+			teamOpts = append(teamOpts, aievo.WithSopExpert(a))
+		case AgentTypeWatcher:
+			teamOpts = append(teamOpts, aievo.WithWatcher(a, spec.Watch.match))
+		default:
+			members = append(members, a)
+		}
+	}
+	teamOpts = append(teamOpts,
+		aievo.WithTeam(members),
+		aievo.WithTeamLeader(b.agents[strings.ToLower(b.team.Leader)]))
+	for _, sub := range b.team.Subscribes {
+		subscribers := make([]schema.Agent, 0, len(sub.Subscribers))
+		for _, name := range sub.Subscribers {
+			subscribers = append(subscribers, b.agents[strings.ToLower(name)])
+		}
+		subscribed := b.agents[strings.ToLower(sub.Subscribed)]
+		if sub.When == "" {
+			teamOpts = append(teamOpts, aievo.WithConditionSubscribe(
+				subscribed, sub.Condition, subscribers...))
+			continue
+		}
+		predicate, err := subscribePredicate(sub)
+		if err != nil {
+			return nil, err
+		}
+		teamOpts = append(teamOpts, aievo.WithPredicateSubscribe(
+			subscribed, predicate, subscribers...))
+	}
+	return aievo.NewAIEvo(append(teamOpts, b.options.teamOpts...)...)
+}
+
+// subscribePredicate matches the condition and the expression of sub.
+func subscribePredicate(sub *Subscribe) (func(schema.Message) bool, error) {
+	when, err := environment.CompileCondition(sub.When)
+	if err != nil {
+		return nil, fmt.Errorf("subscribe: %w", err)
+	}
+	return func(message schema.Message) bool {
+		if sub.Condition != "" && sub.Condition != message.Condition {
+			return false
+		}
+		return when(message)
+	}, nil
+}
+
+func (b *builder) buildAgent(spec *Agent) (schema.Agent, error) {
+	l, err := b.llm(spec.LLM)
+	if err != nil {
+		return nil, fmt.Errorf("llm: %w", err)
+	}
+	opts := []agent.Option{
+		agent.WithName(spec.Name),
+		agent.WithLLM(l),
+		agent.WithEnv(b.env),
+		agent.WithCallback(b.options.callback),
+	}
+	if spec.Desc != "" {
+		opts = append(opts, agent.WithDesc(spec.Desc))
+	}
+	if spec.Role != "" {
+		opts = append(opts, agent.WithRole(spec.Role))
+	}
+	for _, text := range []struct {
+		value, file string
+		opt         func(string) agent.Option
+	}{
+		{spec.Prompt, spec.PromptFile, agent.WithPrompt},
+		{spec.Instruction, spec.InstructionFile, agent.WithInstruction},
+		{spec.Suffix, spec.SuffixFile, agent.WithSuffix},
+	} {
+		v, err := b.text(text.value, text.file)
+		if err != nil {
+			return nil, err
+		}
+		if v != "" {
+			opts = append(opts, text.opt(v))
+		}
+	}
+	for k, v := range spec.Vars {
+		opts = append(opts, agent.WithVars(k, v))
+	}
+	if spec.MaxIterations > 0 {
+		opts = append(opts, agent.WithMaxIterations(spec.MaxIterations))
+	}
+	if spec.UseFunctionCall {
+		opts = append(opts, agent.WithUseFunctionCall(true))
+	}
+
+	tools := make([]tool.Tool, 0)
+	for i, tl := range spec.Tools {
+		built, err := b.tool(tl)
+		if err != nil {
+			return nil, fmt.Errorf("tools[%d]: %w", i, err)
+		}
+		tools = append(tools, built...)
+	}
+	if len(tools) > 0 {
+		opts = append(opts, agent.WithTools(tools))
+	}
+
+	if len(spec.Feedbacks) > 0 {
+		fds := make([]feedback.Feedback, 0, len(spec.Feedbacks))
+		for i, fd := range spec.Feedbacks {
+			built, err := b.feedback(fd)
+			if err != nil {
+				return nil, fmt.Errorf("feedbacks[%d]: %w", i, err)
+			}
+			fds = append(fds, built)
+		}
+		opts = append(opts, agent.WithFeedbacks(fds...))
+	}
+	opts = append(opts, b.options.agentOpts...)
+
+	switch spec.Type {
+	case AgentTypeGraph:
+		// the graph is taken from the sop of the environment
+		return agent.NewGraphAgent(opts...)
+	case AgentTypeSop:
+		return agent.NewSopAgent(opts...)
+	case AgentTypeWatcher:
+		return agent.NewWatcherAgent(opts...)
+	default:
+		return agent.NewBaseAgent(opts...)
+	}
+}
+
+func (b *builder) feedback(spec *Feedback) (feedback.Feedback, error) {
+	if spec.Type == FeedbackContent {
+		return feedback.NewContentFeedback(), nil
+	}
+	l, err := b.llm(spec.LLM)
+	if err != nil {
+		return nil, fmt.Errorf("llm: %w", err)
+	}
+	switch spec.Type {
+	case FeedbackSop:
+		return feedback.NewSopFeedback(l)
+	case FeedbackStability:
+		return feedback.NewStabilityFeedback(l)
+	}
+	opts := make([]feedback.LLMFeedbackOption, 0)
+	p, err := b.text(spec.Prompt, spec.PromptFile)
+	if err != nil {
+		return nil, err
+	}
+	if p != "" {
+		opts = append(opts, feedback.WithPromptTemplate(p))
+	}
+	if spec.Expert > 0 {
+		opts = append(opts, feedback.WithExpertNum(spec.Expert))
+	}
+	return feedback.NewLLMFeedback(l, opts...)
+}
+
+func (b *builder) tool(spec *Tool) ([]tool.Tool, error) {
+	factory, ok := b.options.tools[spec.Name]
+	if !ok {
+		factory, ok = ToolFactories[spec.Name]
+	}
+	if ok {
+		args := make(map[string]string, len(spec.Args))
+		for k, v := range spec.Args {
+			args[k] = os.ExpandEnv(v)
+		}
+		return factory(args)
+	}
+	if t, ok := b.mcp[spec.Name]; ok {
+		return []tool.Tool{t}, nil
+	}
+	return nil, fmt.Errorf("unknown tool %q", spec.Name)
+}
+
+func (b *builder) llm(name string) (llm.LLM, error) {
+	if l, ok := b.llms[name]; ok {
+		return l, nil
+	}
+	if l, ok := b.options.llms[name]; ok {
+		b.llms[name] = l
+		return l, nil
+	}
+	spec := b.team.LLM
+	if name != "" {
+		spec = b.team.LLMs[name]
+	}
+	if spec == nil {
+		return nil, fmt.Errorf("unknown llm %q", name)
+	}
+
+	var (
+		l   llm.LLM
+		err error
+	)
+	switch spec.Provider {
+	case ProviderOllama:
+		opts := []ollama.Option{ollama.WithModel(os.ExpandEnv(spec.Model))}
+		if spec.BaseURL != "" {
+			opts = append(opts, ollama.WithServerURL(os.ExpandEnv(spec.BaseURL)))
+		}
+		l, err = ollama.New(opts...)
+	default:
+		opts := []openai.Option{openai.WithToken(os.ExpandEnv(spec.Token))}
+		if spec.Model != "" {
+			opts = append(opts, openai.WithModel(os.ExpandEnv(spec.Model)))
+		}
+		if spec.BaseURL != "" {
+			opts = append(opts, openai.WithBaseURL(os.ExpandEnv(spec.BaseURL)))
+		}
+		if spec.APIVersion != "" {
+			opts = append(opts, openai.WithAPIVersion(os.ExpandEnv(spec.APIVersion)))
+		}
+		if spec.Organization != "" {
+			opts = append(opts, openai.WithOrganization(os.ExpandEnv(spec.Organization)))
+		}
+		l, err = openai.New(opts...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	b.llms[name] = l
+	return l, nil
+}
+
+func (b *builder) loadMCP() error {
+	b.mcp = make(map[string]tool.Tool)
+	if b.team.MCPServers == nil {
+		return nil
+	}
+	servers, ok := b.team.MCPServers.(string)
+	if !ok {
+		data, err := json.Marshal(b.team.MCPServers)
+		if err != nil {
+			return err
+		}
+		servers = string(data)
+	}
+	tools, err := mcp.New(os.ExpandEnv(servers))
+	if err != nil {
+		return err
+	}
+	for _, t := range tools {
+		b.mcp[t.Name()] = t
+	}
+	return nil
+}
+
+// text returns value, or the content of file when value is empty.
+func (b *builder) text(value, file string) (string, error) {
+	if value != "" || file == "" {
+		return value, nil
+	}
+	if !filepath.IsAbs(file) && b.team.dir != "" {
+		file = filepath.Join(b.team.dir, file)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func (w *Watch) match(message schema.Message) bool {
+	if w == nil {
+		return true
+	}
+	return (w.Sender == "" || w.Sender == message.Sender) &&
+		(w.Receiver == "" || w.Receiver == message.Receiver) &&
+		(w.Condition == "" || w.Condition == message.Condition)
+}
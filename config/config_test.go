@@ -0,0 +1,165 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/antgroup/aievo/llm/openai"
+	"github.com/antgroup/aievo/tool"
+	"github.com/antgroup/aievo/tool/calculator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const _team = `
+llm:
+  provider: openai
+  model: gpt-4o
+  token: ${CONFIG_TEST_TOKEN}
+agents:
+  - name: Leader
+    description: leader of the team
+    prompt_file: leader.txt
+    tools:
+      - calculator
+      - name: echo
+        args:
+          prefix: ">"
+  - name: Writer
+    description: writer of the team
+    vars:
+      style: formal
+    feedbacks:
+      - type: content
+      - type: llm
+        expert: 2
+  - name: Watcher
+    type: watcher
+    watch:
+      sender: Leader
+      receiver: ALL
+leader: Leader
+subscribe_mode: custom
+subscribes:
+  - subscribed: Leader
+    subscribers: [Writer]
+max_turns: 5
+`
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "team.yaml"), []byte(_team), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "leader.txt"), []byte("you are the leader"), 0o644))
+	t.Setenv("CONFIG_TEST_TOKEN", "test")
+
+	spec, err := LoadFile(filepath.Join(dir, "team.yaml"))
+	require.NoError(t, err)
+	assert.Len(t, spec.Agents, 3)
+	assert.Equal(t, []*Tool{{Name: "calculator"}, {Name: "echo", Args: map[string]string{"prefix": ">"}}},
+		spec.Agents[0].Tools)
+
+	var args map[string]string
+	team, err := spec.Build(WithToolFactory("echo", func(a map[string]string) ([]tool.Tool, error) {
+		args = a
+		return []tool.Tool{calculator.Calculator{}}, nil
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"prefix": ">"}, args)
+	assert.Equal(t, "Leader", team.GetTeamLeader().Name())
+	assert.Len(t, team.GetTeam(), 2)
+	assert.Equal(t, "Watcher", team.Watcher.Name())
+	assert.Equal(t, 5, team.MaxTurn)
+	assert.Len(t, team.GetTeamLeader().Tools(), 2)
+}
+
+func TestParseJSON(t *testing.T) {
+	spec, err := Parse([]byte(`{
+		"llm": {"token": "test"},
+		"agents": [{"name": "Solo", "description": "works alone"}],
+		"leader": "Solo"
+	}`), FormatJSON)
+	require.NoError(t, err)
+	_, err = spec.Build()
+	require.NoError(t, err)
+
+	spec.Agents[0].Tools = []*Tool{{Name: "hammer"}}
+	_, err = spec.Build()
+	assert.EqualError(t, err, `agents[0]: tools[0]: unknown tool "hammer"`)
+
+	// a spec built without Parse is validated as well
+	spec.Agents[0].Tools = nil
+	spec.Subscribes = []*Subscribe{{Subscribed: "Solo", Subscribers: []string{"Nobody"}}}
+	_, err = spec.Build()
+	var fe *FieldError
+	require.ErrorAs(t, err, &fe)
+	assert.Equal(t, "subscribes[0].subscribers[0]", fe.Path)
+
+	_, err = Parse([]byte(`{"llm": {"token": "test"}, "unknown": 1}`), FormatJSON)
+	assert.Error(t, err)
+	_, err = Parse(nil, "toml")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestValidate(t *testing.T) {
+	_, err := Parse([]byte(`
+agents:
+  - name: Leader
+    description: leader
+    llm: missing
+    tools: [""]
+  - name: leader
+    type: robot
+  - name: Expert
+    type: sop
+leader: Expert
+subscribe_mode: random
+subscribes:
+  - subscribed: Nobody
+    subscribers: [Leader]
+  - when: content ==
+    subscribers: [Leader]
+`), FormatYAML)
+	require.Error(t, err)
+
+	paths := make([]string, 0)
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var fe *FieldError
+		require.True(t, errors.As(e, &fe))
+		paths = append(paths, fe.Path)
+	}
+	assert.ElementsMatch(t, []string{
+		"agents[0].llm",
+		"agents[0].tools[0]",
+		"agents[1].description",
+		"agents[1].type",
+		"agents[1].llm",
+		"agents[1].name",
+		"agents[2].llm",
+		"leader",
+		"subscribe_mode",
+		"subscribes[0].subscribed",
+		"subscribes[1].when",
+	}, paths)
+}
+
+func TestBuildWithLLM(t *testing.T) {
+	spec := &Team{
+		Agents: []*Agent{{
+			Name:      "Leader",
+			Desc:      "leader of the team",
+			Feedbacks: []*Feedback{{Type: FeedbackContent}},
+		}},
+		Leader: "Leader",
+	}
+	var fe *FieldError
+	require.ErrorAs(t, spec.Validate(), &fe)
+	assert.Equal(t, "agents[0].llm", fe.Path)
+
+	l, err := openai.New(openai.WithToken("test"))
+	require.NoError(t, err)
+	team, err := spec.Build(WithLLM("", l))
+	require.NoError(t, err)
+	assert.Equal(t, "Leader", team.GetTeamLeader().Name())
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+)
+
+var ErrUnknownFormat = errors.New("unknown config format")
+
+// LoadFile reads and validates the team spec in path, the format is
+// decided by the file extension. Relative paths in the spec are resolved
+// against the directory of path.
+func LoadFile(path string) (*Team, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	format := FormatYAML
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		format = FormatJSON
+	}
+	team, err := Parse(data, format)
+	if err != nil {
+		return nil, err
+	}
+	team.dir = filepath.Dir(path)
+	return team, nil
+}
+
+// Parse decodes and validates a team spec in yaml or json format.
+func Parse(data []byte, format string) (*Team, error) {
+	raw := make(map[string]any)
+	switch strings.ToLower(format) {
+	case FormatYAML, "yml":
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+	case FormatJSON:
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+
+	team := &Team{}
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:  toolHook,
+		ErrorUnused: true,
+		Result:      team,
+		TagName:     "json",
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = decoder.Decode(raw); err != nil {
+		return nil, err
+	}
+	if err = team.Validate(); err != nil {
+		return nil, err
+	}
+	return team, nil
+}
+
+// toolHook allows a tool to be written as its name only.
+func toolHook(from reflect.Type, to reflect.Type, data any) (any, error) {
+	if from.Kind() == reflect.String && to == reflect.TypeOf(Tool{}) {
+		return map[string]any{"name": data}, nil
+	}
+	return data, nil
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"fmt"
+)
+
+const (
+	AgentTypeBase    = "base"
+	AgentTypeGraph   = "graph"
+	AgentTypeSop     = "sop"
+	AgentTypeWatcher = "watcher"
+)
+
+const (
+	ProviderOpenAI = "openai"
+	ProviderOllama = "ollama"
+)
+
+const (
+	FeedbackContent   = "content"
+	FeedbackLLM       = "llm"
+	FeedbackSop       = "sop"
+	FeedbackStability = "stability"
+)
+
+// Team is the declarative specification of a team.
+type Team struct {
+	// LLM is the default model used by agents without their own llm.
+	LLM *LLM `json:"llm"`
+	// LLMs are named models that agents can refer to.
+	LLMs map[string]*LLM `json:"llms"`
+
+	Agents []*Agent `json:"agents"`
+	Leader string   `json:"leader"`
+
+	// SubscribeMode is one of default, leader, all and custom.
+	SubscribeMode string       `json:"subscribe_mode"`
+	Subscribes    []*Subscribe `json:"subscribes"`
+
+	SOP      string `json:"sop"`
+	SOPFile  string `json:"sop_file"`
+	MaxTurn  int    `json:"max_turns"`
+	MaxToken int    `json:"max_tokens"`
+
+	// MCPServers is the mcp servers config, either as a JSON string or as
+	// an object with the "mcpServers" key. Tools of the servers can be
+	// referred to by name from agents.
+	MCPServers any `json:"mcp_servers"`
+
+	// dir is the directory relative file paths are resolved against.
+	dir string
+}
+
+// LLM describes how to connect a model provider.
+type LLM struct {
+	Provider     string `json:"provider"`
+	Model        string `json:"model"`
+	Token        string `json:"token"`
+	BaseURL      string `json:"base_url"`
+	APIVersion   string `json:"api_version"`
+	Organization string `json:"organization"`
+}
+
+// Agent describes a single agent of the team.
+type Agent struct {
+	Name string `json:"name"`
+	Desc string `json:"description"`
+	Role string `json:"role"`
+	// Type is one of base, graph, sop and watcher, base by default.
+	Type string `json:"type"`
+	// LLM refers to Team.LLMs, the default llm is used when empty.
+	LLM string `json:"llm"`
+
+	Prompt          string `json:"prompt"`
+	PromptFile      string `json:"prompt_file"`
+	Instruction     string `json:"instruction"`
+	InstructionFile string `json:"instruction_file"`
+	Suffix          string `json:"suffix"`
+	SuffixFile      string `json:"suffix_file"`
+
+	Tools           []*Tool           `json:"tools"`
+	Vars            map[string]string `json:"vars"`
+	Feedbacks       []*Feedback       `json:"feedbacks"`
+	MaxIterations   int               `json:"max_iterations"`
+	UseFunctionCall bool              `json:"use_function_call"`
+
+	// Watch is the condition on which a watcher agent is triggered.
+	Watch *Watch `json:"watch"`
+}
+
+// Tool refers to a tool registered in ToolFactories or provided by the
+// mcp servers. It can be written as a plain name.
+type Tool struct {
+	Name string            `json:"name"`
+	Args map[string]string `json:"args"`
+}
+
+// Feedback describes a feedback of an agent.
+type Feedback struct {
+	// Type is one of content, llm, sop and stability.
+	Type       string `json:"type"`
+	LLM        string `json:"llm"`
+	Prompt     string `json:"prompt"`
+	PromptFile string `json:"prompt_file"`
+	Expert     int    `json:"expert"`
+}
+
+// Subscribe makes subscribers receive the messages sent by subscribed,
+// only the messages with the condition when it is not empty. When is an
+// expression the messages must match too, see
+// environment.CompileCondition; subscribed may be empty then to receive
+// the messages of any sender.
+type Subscribe struct {
+	Subscribed  string   `json:"subscribed"`
+	Condition   string   `json:"condition"`
+	When        string   `json:"when"`
+	Subscribers []string `json:"subscribers"`
+}
+
+// Watch triggers the watcher for messages matching all the fields, an
+// empty field matches everything.
+type Watch struct {
+	Sender    string `json:"sender"`
+	Receiver  string `json:"receiver"`
+	Condition string `json:"condition"`
+}
+
+// FieldError is a validation error of the field at Path.
+type FieldError struct {
+	Path string
+	Err  error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Err.Error())
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+func fieldError(path string, format string, args ...any) error {
+	return &FieldError{Path: path, Err: fmt.Errorf(format, args...)}
+}
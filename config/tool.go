@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/antgroup/aievo/tool"
+	"github.com/antgroup/aievo/tool/arxiv"
+	"github.com/antgroup/aievo/tool/bash"
+	"github.com/antgroup/aievo/tool/calculator"
+	"github.com/antgroup/aievo/tool/code"
+	"github.com/antgroup/aievo/tool/file"
+	"github.com/antgroup/aievo/tool/reader"
+	"github.com/antgroup/aievo/tool/search"
+	"github.com/antgroup/aievo/tool/wikipedia"
+)
+
+// ToolFactory builds the tools referred to by a name in the spec.
+type ToolFactory func(args map[string]string) ([]tool.Tool, error)
+
+// ToolFactories are the tools that can be referred to by name, more can be
+// registered before loading a spec or passed with WithToolFactory.
+var ToolFactories = map[string]ToolFactory{
+	"calculator": func(map[string]string) ([]tool.Tool, error) {
+		return []tool.Tool{calculator.Calculator{}}, nil
+	},
+	"terminal": func(map[string]string) ([]tool.Tool, error) {
+		return single(bash.New())
+	},
+	"golangRunner": codeRunner("golang"),
+	"pythonRunner": codeRunner("python"),
+	"javaRunner":   codeRunner("java"),
+	"file": func(args map[string]string) ([]tool.Tool, error) {
+		if args["workspace"] == "" {
+			return nil, fmt.Errorf("arg workspace is required")
+		}
+		return file.GetFileRelatedTools(args["workspace"])
+	},
+	"websiteReader": func(map[string]string) ([]tool.Tool, error) {
+		return single(reader.NewReader(reader.WithReaderType("website")))
+	},
+	"pdfReader": func(map[string]string) ([]tool.Tool, error) {
+		return single(reader.NewReader(reader.WithReaderType("pdf")))
+	},
+	"search": func(args map[string]string) ([]tool.Tool, error) {
+		topK, err := intArg(args, "top_k")
+		if err != nil {
+			return nil, err
+		}
+		return single(search.New(
+			search.WithEngine(args["engine"]),
+			search.WithApiKey(args["api_key"]),
+			search.WithTopK(topK)))
+	},
+	"wikipedia": func(args map[string]string) ([]tool.Tool, error) {
+		topK, err := intArg(args, "top_k")
+		if err != nil {
+			return nil, err
+		}
+		opts := make([]wikipedia.Option, 0)
+		if topK > 0 {
+			opts = append(opts, wikipedia.WithTopK(topK))
+		}
+		if args["language"] != "" {
+			opts = append(opts, wikipedia.WithLanguageCode(args["language"]))
+		}
+		return single(wikipedia.New(opts...))
+	},
+	"arxiv": func(args map[string]string) ([]tool.Tool, error) {
+		topK, err := intArg(args, "top_k")
+		if err != nil {
+			return nil, err
+		}
+		opts := make([]arxiv.Option, 0)
+		if topK > 0 {
+			opts = append(opts, arxiv.WithTopk(topK))
+		}
+		return single(arxiv.New(opts...))
+	},
+}
+
+func codeRunner(lang string) ToolFactory {
+	return func(map[string]string) ([]tool.Tool, error) {
+		return single(code.New(code.WithProgramLangType(lang)))
+	}
+}
+
+func single[T tool.Tool](t T, err error) ([]tool.Tool, error) {
+	if err != nil {
+		return nil, err
+	}
+	return []tool.Tool{t}, nil
+}
+
+func intArg(args map[string]string, name string) (int, error) {
+	if args[name] == "" {
+		return 0, nil
+	}
+	v, err := strconv.Atoi(args[name])
+	if err != nil {
+		return 0, fmt.Errorf("arg %s: %w", name, err)
+	}
+	return v, nil
+}
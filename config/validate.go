@@ -0,0 +1,219 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/antgroup/aievo/environment"
+	"github.com/antgroup/aievo/llm"
+)
+
+// Validate checks the spec and returns all the problems found, each one
+// pointing at the offending path.
+func (t *Team) Validate() error {
+	return t.validate(nil)
+}
+
+// validate is Validate where the llms given to Build also count as defined.
+func (t *Team) validate(llms map[string]llm.LLM) error {
+	errs := make([]error, 0)
+	if t.LLM != nil {
+		errs = append(errs, t.LLM.validate("llm")...)
+	}
+	for name, l := range t.LLMs {
+		errs = append(errs, l.validate(fmt.Sprintf("llms.%s", name))...)
+	}
+
+	if len(t.Agents) == 0 {
+		errs = append(errs, fieldError("agents", "at least one agent is required"))
+	}
+	names := make(map[string]*Agent)
+	sops, watchers := 0, 0
+	for i, a := range t.Agents {
+		path := fmt.Sprintf("agents[%d]", i)
+		if a == nil {
+			errs = append(errs, fieldError(path, "agent is empty"))
+			continue
+		}
+		errs = append(errs, t.validateAgent(path, a, llms)...)
+		if a.Name == "" {
+			continue
+		}
+		if _, ok := names[strings.ToLower(a.Name)]; ok {
+			errs = append(errs, fieldError(path+".name", "duplicate agent name %q", a.Name))
+		}
+		names[strings.ToLower(a.Name)] = a
+		switch a.Type {
+		case AgentTypeSop:
+			sops++
+		case AgentTypeWatcher:
+			watchers++
+		}
+	}
+	if sops > 1 {
+		errs = append(errs, fieldError("agents", "at most one sop agent is allowed"))
+	}
+	if watchers > 1 {
+		errs = append(errs, fieldError("agents", "at most one watcher agent is allowed"))
+	}
+
+	member := func(path, name string) {
+		a, ok := names[strings.ToLower(name)]
+		if !ok {
+			errs = append(errs, fieldError(path, "unknown agent %q", name))
+			return
+		}
+		if !a.isMember() {
+			errs = append(errs, fieldError(path, "agent %q is a %s agent, not a team member", name, a.Type))
+		}
+	}
+	if t.Leader == "" {
+		errs = append(errs, fieldError("leader", "leader is required"))
+	} else {
+		member("leader", t.Leader)
+	}
+	if _, ok := subscribeModes[strings.ToLower(t.SubscribeMode)]; !ok {
+		errs = append(errs, fieldError("subscribe_mode",
+			"unknown subscribe mode %q, expect one of default, leader, all and custom", t.SubscribeMode))
+	}
+	for i, sub := range t.Subscribes {
+		path := fmt.Sprintf("subscribes[%d]", i)
+		if sub == nil {
+			errs = append(errs, fieldError(path, "subscribe is empty"))
+			continue
+		}
+		if sub.Subscribed != "" || sub.When == "" {
+			member(path+".subscribed", sub.Subscribed)
+		}
+		if sub.When != "" {
+			if _, err := environment.CompileCondition(sub.When); err != nil {
+				errs = append(errs, fieldError(path+".when", "invalid expression: %v", err))
+			}
+		}
+		if len(sub.Subscribers) == 0 {
+			errs = append(errs, fieldError(path+".subscribers", "at least one subscriber is required"))
+		}
+		for j, subscriber := range sub.Subscribers {
+			member(fmt.Sprintf("%s.subscribers[%d]", path, j), subscriber)
+		}
+	}
+	if t.SOP != "" && t.SOPFile != "" {
+		errs = append(errs, fieldError("sop_file", "sop and sop_file cannot be both set"))
+	}
+	if t.MaxTurn < 0 {
+		errs = append(errs, fieldError("max_turns", "must not be negative"))
+	}
+	if t.MaxToken < 0 {
+		errs = append(errs, fieldError("max_tokens", "must not be negative"))
+	}
+	switch t.MCPServers.(type) {
+	case nil, string, map[string]any:
+	default:
+		errs = append(errs, fieldError("mcp_servers", "expect a json string or an object"))
+	}
+	return errors.Join(errs...)
+}
+
+func (t *Team) validateAgent(path string, a *Agent, llms map[string]llm.LLM) []error {
+	errs := make([]error, 0)
+	if a.Name == "" {
+		errs = append(errs, fieldError(path+".name", "name is required"))
+	}
+	if a.Desc == "" && a.isMember() {
+		errs = append(errs, fieldError(path+".description", "description is required"))
+	}
+	switch a.Type {
+	case "", AgentTypeBase, AgentTypeGraph, AgentTypeSop, AgentTypeWatcher:
+	default:
+		errs = append(errs, fieldError(path+".type",
+			"unknown agent type %q, expect one of base, graph, sop and watcher", a.Type))
+	}
+	if a.Type == AgentTypeGraph && t.SOP == "" && t.SOPFile == "" {
+		errs = append(errs, fieldError(path+".type", "graph agent requires sop or sop_file"))
+	}
+	if a.Watch != nil && a.Type != AgentTypeWatcher {
+		errs = append(errs, fieldError(path+".watch", "only watcher agent can watch"))
+	}
+	if a.Prompt != "" && a.PromptFile != "" {
+		errs = append(errs, fieldError(path+".prompt_file", "prompt and prompt_file cannot be both set"))
+	}
+	if a.Instruction != "" && a.InstructionFile != "" {
+		errs = append(errs, fieldError(path+".instruction_file",
+			"instruction and instruction_file cannot be both set"))
+	}
+	if a.Suffix != "" && a.SuffixFile != "" {
+		errs = append(errs, fieldError(path+".suffix_file", "suffix and suffix_file cannot be both set"))
+	}
+	errs = append(errs, t.validateLLMRef(path+".llm", a.LLM, llms)...)
+
+	for i, tl := range a.Tools {
+		toolPath := fmt.Sprintf("%s.tools[%d]", path, i)
+		// names are resolved by Build, as tools can also come from the build
+		// options and the mcp servers
+		if tl == nil || tl.Name == "" {
+			errs = append(errs, fieldError(toolPath, "tool name is required"))
+		}
+	}
+	for i, fd := range a.Feedbacks {
+		fdPath := fmt.Sprintf("%s.feedbacks[%d]", path, i)
+		if fd == nil {
+			errs = append(errs, fieldError(fdPath, "feedback is empty"))
+			continue
+		}
+		switch fd.Type {
+		case FeedbackContent, FeedbackLLM, FeedbackSop, FeedbackStability:
+		default:
+			errs = append(errs, fieldError(fdPath+".type",
+				"unknown feedback type %q, expect one of content, llm, sop and stability", fd.Type))
+		}
+		if fd.Prompt != "" && fd.PromptFile != "" {
+			errs = append(errs, fieldError(fdPath+".prompt_file", "prompt and prompt_file cannot be both set"))
+		}
+		if fd.Expert < 0 {
+			errs = append(errs, fieldError(fdPath+".expert", "must not be negative"))
+		}
+		if fd.Type != FeedbackContent {
+			errs = append(errs, t.validateLLMRef(fdPath+".llm", fd.LLM, llms)...)
+		}
+	}
+	return errs
+}
+
+func (t *Team) validateLLMRef(path, name string, llms map[string]llm.LLM) []error {
+	if _, ok := llms[name]; ok {
+		return nil
+	}
+	if name == "" {
+		if t.LLM == nil {
+			return []error{fieldError(path, "no llm is set and there is no default llm")}
+		}
+		return nil
+	}
+	if _, ok := t.LLMs[name]; !ok {
+		return []error{fieldError(path, "unknown llm %q", name)}
+	}
+	return nil
+}
+
+func (l *LLM) validate(path string) []error {
+	if l == nil {
+		return []error{fieldError(path, "llm is empty")}
+	}
+	errs := make([]error, 0)
+	switch l.Provider {
+	case "", ProviderOpenAI:
+	case ProviderOllama:
+		if l.Model == "" {
+			errs = append(errs, fieldError(path+".model", "model is required for ollama"))
+		}
+	default:
+		errs = append(errs, fieldError(path+".provider",
+			"unknown provider %q, expect one of openai and ollama", l.Provider))
+	}
+	return errs
+}
+
+func (a *Agent) isMember() bool {
+	return a.Type != AgentTypeSop && a.Type != AgentTypeWatcher
+}
@@ -18,6 +18,22 @@ type GraphDriver struct {
 	Current []*graphviz.Node
 	Execute *graphviz.Graph
 	nodes   []*graphviz.Node
+
+	// sop and updates are kept to rebuild the graphs from a GraphState
+	sop     string
+	updates []GraphUpdate
+}
+
+// GraphState is the serializable progress of a GraphDriver, the graph and
+// the updates applied to it.
+type GraphState struct {
+	SOP     string        `json:"sop"`
+	Updates []GraphUpdate `json:"updates,omitempty"`
+}
+
+type GraphUpdate struct {
+	Steps   []schema.StepAction `json:"steps,omitempty"`
+	Actions []schema.StepAction `json:"actions,omitempty"`
 }
 
 func NewGraphDriver() *GraphDriver {
@@ -38,6 +54,7 @@ func (g *GraphDriver) InitGraph(_ context.Context, sop string) (Driver, error) {
 	}
 	g.Graph = graph
 	g.Current = make([]*graphviz.Node, 0)
+	g.sop, g.updates = sop, nil
 	execute, err := graphviz.New(context.Background())
 	if err != nil {
 		return nil, err
@@ -95,7 +112,39 @@ func (g *GraphDriver) UpdateGraphState(ctx context.Context,
 	if err != nil {
 		return err
 	}
-	return g.updateComment(ctx, steps)
+	err = g.updateComment(ctx, steps)
+	if err != nil {
+		return err
+	}
+	g.updates = append(g.updates, GraphUpdate{
+		Steps:   append([]schema.StepAction{}, steps...),
+		Actions: append([]schema.StepAction{}, actions...),
+	})
+	return nil
+}
+
+// State returns the progress of the driver, to restore it later.
+func (g *GraphDriver) State() *GraphState {
+	return &GraphState{
+		SOP:     g.sop,
+		Updates: append([]GraphUpdate{}, g.updates...),
+	}
+}
+
+// Restore rebuilds the graphs of state, replaying its updates.
+func (g *GraphDriver) Restore(ctx context.Context, state *GraphState) error {
+	if state == nil || state.SOP == "" {
+		return nil
+	}
+	if _, err := g.InitGraph(ctx, state.SOP); err != nil {
+		return err
+	}
+	for _, update := range state.Updates {
+		if err := g.UpdateGraphState(ctx, update.Steps, update.Actions); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // updateNodes 从 Graph 的node里面，添加到 Execute 里面
@@ -18,6 +18,13 @@ type Driver interface {
 	TmpRender() string
 }
 
+// StatefulDriver is implemented by the drivers able to save and restore
+// their progress.
+type StatefulDriver interface {
+	State() *GraphState
+	Restore(ctx context.Context, state *GraphState) error
+}
+
 const (
 	_executing      = "green"
 	_done           = "red"
@@ -0,0 +1,136 @@
+package environment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/antgroup/aievo/schema"
+)
+
+// Blackboard is the schema.Blackboard of an environment, it is safe for
+// concurrent use. The writes are told to the callback of the environment
+// when it is a callback.BlackboardHandler.
+type Blackboard struct {
+	entries  map[string]schema.BlackboardEntry
+	onChange func(ctx context.Context, old, new *schema.BlackboardEntry)
+
+	mu sync.RWMutex
+}
+
+var (
+	_ schema.Blackboard            = (*Blackboard)(nil)
+	_ schema.BlackboardEnvironment = (*Environment)(nil)
+)
+
+func NewBlackboard() *Blackboard {
+	return &Blackboard{entries: make(map[string]schema.BlackboardEntry)}
+}
+
+// OnChange sets the function called after each write, with the entry
+// replaced, nil for a new key, and the new one, nil for a deleted key.
+func (b *Blackboard) OnChange(fn func(ctx context.Context, old, new *schema.BlackboardEntry)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.onChange = fn
+}
+
+func (b *Blackboard) Get(key string) (schema.BlackboardEntry, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	entry, ok := b.entries[key]
+	return entry, ok
+}
+
+func (b *Blackboard) Set(ctx context.Context, key string, value any) (schema.BlackboardEntry, error) {
+	return b.set(ctx, key, value, -1)
+}
+
+func (b *Blackboard) SetIf(ctx context.Context, key string, value any, version int) (schema.BlackboardEntry, error) {
+	return b.set(ctx, key, value, version)
+}
+
+// set writes value when the key is at version, at any version when it is
+// negative.
+func (b *Blackboard) set(ctx context.Context, key string, value any, version int) (schema.BlackboardEntry, error) {
+	raw, ok := value.(json.RawMessage)
+	if !ok {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return schema.BlackboardEntry{}, fmt.Errorf("blackboard key %s: %w", key, err)
+		}
+		raw = data
+	} else if !json.Valid(raw) {
+		return schema.BlackboardEntry{}, fmt.Errorf("blackboard key %s: invalid JSON value", key)
+	}
+
+	b.mu.Lock()
+	old, exists := b.entries[key]
+	if version >= 0 && version != old.Version {
+		b.mu.Unlock()
+		return schema.BlackboardEntry{}, fmt.Errorf("%w: %s is at version %d, not %d",
+			schema.ErrBlackboardVersion, key, old.Version, version)
+	}
+	entry := schema.BlackboardEntry{
+		Key:     key,
+		Value:   append(json.RawMessage{}, raw...),
+		Version: old.Version + 1,
+		Author:  schema.AgentFromContext(ctx),
+		Time:    time.Now(),
+	}
+	b.entries[key] = entry
+	onChange := b.onChange
+	b.mu.Unlock()
+
+	if onChange != nil {
+		if exists {
+			onChange(ctx, &old, &entry)
+		} else {
+			onChange(ctx, nil, &entry)
+		}
+	}
+	return entry, nil
+}
+
+func (b *Blackboard) Delete(ctx context.Context, key string) error {
+	b.mu.Lock()
+	old, ok := b.entries[key]
+	if !ok {
+		b.mu.Unlock()
+		return fmt.Errorf("%w: %s", schema.ErrBlackboardKeyNotFound, key)
+	}
+	delete(b.entries, key)
+	onChange := b.onChange
+	b.mu.Unlock()
+
+	if onChange != nil {
+		onChange(ctx, &old, nil)
+	}
+	return nil
+}
+
+func (b *Blackboard) List() []schema.BlackboardEntry {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	entries := make([]schema.BlackboardEntry, 0, len(b.entries))
+	for _, entry := range b.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+	return entries
+}
+
+// restore replaces the entries, without telling the changes.
+func (b *Blackboard) restore(entries []schema.BlackboardEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entries = make(map[string]schema.BlackboardEntry, len(entries))
+	for _, entry := range entries {
+		b.entries[entry.Key] = entry
+	}
+}
@@ -0,0 +1,57 @@
+package environment
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/antgroup/aievo/callback"
+	"github.com/antgroup/aievo/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlackboard(t *testing.T) {
+	ctx := schema.ContextWithAgent(context.Background(), "planner")
+	var log bytes.Buffer
+	env := NewEnv()
+	env.Callback = callback.NewRecorder(&log)
+
+	entry, err := env.Blackboard.Set(ctx, "outline", []string{"intro"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, entry.Version)
+	_, err = env.Blackboard.SetIf(ctx, "outline", []string{"intro", "body"}, 1)
+	require.NoError(t, err)
+	_, err = env.Blackboard.SetIf(ctx, "outline", []string{}, 1)
+	assert.ErrorIs(t, err, schema.ErrBlackboardVersion)
+	_, err = env.Blackboard.Set(ctx, "votes", json.RawMessage(`{"yes": 2}`))
+	require.NoError(t, err)
+	require.NoError(t, env.Blackboard.Delete(ctx, "votes"))
+	assert.ErrorIs(t, env.Blackboard.Delete(ctx, "votes"), schema.ErrBlackboardKeyNotFound)
+
+	events := make([]callback.Event, 0)
+	for _, line := range strings.Split(strings.TrimSpace(log.String()), "\n") {
+		event := callback.Event{}
+		require.NoError(t, json.Unmarshal([]byte(line), &event))
+		events = append(events, event)
+	}
+	require.Len(t, events, 4)
+	assert.Nil(t, events[0].OldEntry)
+	assert.Equal(t, "planner", events[1].Entry.Author)
+	assert.Equal(t, 1, events[1].OldEntry.Version)
+	assert.Nil(t, events[3].Entry)
+
+	// the blackboard is part of the state, but not of a clone
+	state, err := env.Snapshot(ctx)
+	require.NoError(t, err)
+	require.Len(t, state.Blackboard, 1)
+	clone := env.Clone(nil)
+	assert.Empty(t, clone.Blackboard.List())
+	require.NoError(t, clone.Restore(ctx, state))
+	outline, version, err := schema.GetBlackboardValue[[]string](clone.GetBlackboard(), "outline")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"intro", "body"}, outline)
+	assert.Equal(t, 2, version)
+}
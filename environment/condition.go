@@ -0,0 +1,139 @@
+package environment
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/antgroup/aievo/schema"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+const _maxConditionSteps = 100000
+
+var (
+	_conditionOptions = &syntax.FileOptions{}
+	// _conditionNames are the names an expression can refer to
+	_conditionNames = []string{"sender", "receiver", "content", "condition",
+		"type", "metadata", "re_search", "re_find"}
+)
+
+// CompileCondition compiles a starlark expression into a predicate over
+// messages, for the subscriptions. The expression can refer to sender,
+// receiver, content, condition, type and metadata of the message, and to
+// re_search(pattern, s), true when s matches pattern, and re_find(pattern,
+// s), the first match of pattern in s or its first group, or None. e.g.
+//
+//	"refund" in content and float(metadata.get("amount", 0)) > 1000
+//
+// The expression cannot change anything, and a message it fails on does
+// not match.
+func CompileCondition(expr string) (func(message schema.Message) bool, error) {
+	parsed, err := _conditionOptions.ParseExpr("condition", expr, 0)
+	if err != nil {
+		return nil, err
+	}
+	// resolve the names once, so unknown names fail now
+	globals := make(starlark.StringDict, len(_conditionNames))
+	for _, name := range _conditionNames {
+		globals[name] = starlark.None
+	}
+	if _, err = starlark.ExprFuncOptions(_conditionOptions, "condition", expr, globals); err != nil {
+		return nil, err
+	}
+	return func(message schema.Message) bool {
+		thread := &starlark.Thread{Name: "condition"}
+		thread.SetMaxExecutionSteps(_maxConditionSteps)
+		v, err := starlark.EvalExprOptions(_conditionOptions, thread, parsed, messageGlobals(message))
+		if err != nil {
+			return false
+		}
+		return bool(v.Truth())
+	}, nil
+}
+
+func messageGlobals(message schema.Message) starlark.StringDict {
+	return starlark.StringDict{
+		"sender":    starlark.String(message.Sender),
+		"receiver":  starlark.String(message.Receiver),
+		"content":   starlark.String(message.Content),
+		"condition": starlark.String(message.Condition),
+		"type":      starlark.String(message.Type),
+		"metadata":  toStarlark(message.Metadata),
+		"re_search": starlark.NewBuiltin("re_search", reSearch),
+		"re_find":   starlark.NewBuiltin("re_find", reFind),
+	}
+}
+
+func reSearch(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple,
+	kwargs []starlark.Tuple) (starlark.Value, error) {
+	var pattern, s string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 2, &pattern, &s); err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+	return starlark.Bool(re.MatchString(s)), nil
+}
+
+func reFind(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple,
+	kwargs []starlark.Tuple) (starlark.Value, error) {
+	var pattern, s string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 2, &pattern, &s); err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+	match := re.FindStringSubmatch(s)
+	switch {
+	case match == nil:
+		return starlark.None, nil
+	case len(match) > 1:
+		return starlark.String(match[1]), nil
+	}
+	return starlark.String(match[0]), nil
+}
+
+// toStarlark converts a decoded json value.
+func toStarlark(v any) starlark.Value {
+	switch v := v.(type) {
+	case nil:
+		return starlark.None
+	case string:
+		return starlark.String(v)
+	case bool:
+		return starlark.Bool(v)
+	case int:
+		return starlark.MakeInt(v)
+	case int64:
+		return starlark.MakeInt64(v)
+	case float64:
+		if v == float64(int64(v)) {
+			return starlark.MakeInt64(int64(v))
+		}
+		return starlark.Float(v)
+	case []any:
+		list := make([]starlark.Value, 0, len(v))
+		for _, item := range v {
+			list = append(list, toStarlark(item))
+		}
+		return starlark.NewList(list)
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		dict := starlark.NewDict(len(v))
+		for _, k := range keys {
+			_ = dict.SetKey(starlark.String(k), toStarlark(v[k]))
+		}
+		return dict
+	}
+	return starlark.String(fmt.Sprint(v))
+}
@@ -0,0 +1,85 @@
+package environment
+
+import (
+	"context"
+	"testing"
+
+	"github.com/antgroup/aievo/llm"
+	"github.com/antgroup/aievo/schema"
+	"github.com/antgroup/aievo/tool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type namedAgent string
+
+func (a namedAgent) Run(context.Context, []schema.Message, ...llm.GenerateOption) (*schema.Generation, error) {
+	return nil, nil
+}
+func (a namedAgent) Name() string                 { return string(a) }
+func (a namedAgent) Description() string          { return string(a) }
+func (a namedAgent) WithEnv(_ schema.Environment) {}
+func (a namedAgent) Env() schema.Environment      { return nil }
+func (a namedAgent) Tools() []tool.Tool           { return nil }
+
+func TestCompileCondition(t *testing.T) {
+	refund, err := CompileCondition(`"refund" in content.lower() and ` +
+		`float(re_find("(?i)refund of (\\d+)", content) or metadata.get("amount", 0)) > 1000`)
+	require.NoError(t, err)
+	assert.True(t, refund(schema.Message{Content: "Refund of 1500 approved"}))
+	assert.False(t, refund(schema.Message{Content: "refund of 20 approved"}))
+	assert.True(t, refund(schema.Message{Content: "a refund", Metadata: map[string]any{"amount": 2000.0}}))
+	assert.False(t, refund(schema.Message{Content: "no money"}))
+
+	sender, err := CompileCondition(`sender == "Leader" and type == "MSG" and re_search("^urgent", content)`)
+	require.NoError(t, err)
+	assert.True(t, sender(schema.Message{Sender: "Leader", Type: "MSG", Content: "urgent: fix it"}))
+
+	_, err = CompileCondition(`content ==`)
+	assert.Error(t, err)
+	_, err = CompileCondition(`unknown == 1`)
+	assert.Error(t, err)
+
+	loop, err := CompileCondition(`[x for x in range(100000000)]`)
+	require.NoError(t, err)
+	assert.False(t, loop(schema.Message{}))
+}
+
+func TestPredicateSubscribe(t *testing.T) {
+	leader, writer, auditor := namedAgent("Leader"), namedAgent("Writer"), namedAgent("Auditor")
+	refund, err := CompileCondition(`metadata.get("amount", 0) > 1000`)
+	require.NoError(t, err)
+	team := NewTeam()
+	team.AddMembers(leader, writer, auditor)
+	team.Subscribes = append(team.Subscribes, schema.Subscribe{
+		Subscriber: auditor,
+		Predicate:  refund,
+	})
+
+	assert.Equal(t, []string{"Auditor"}, team.GetMsgSubMembers(&schema.Message{
+		Sender: "Leader", Receiver: "Writer", Metadata: map[string]any{"amount": 5000},
+	}))
+	assert.Empty(t, team.GetMsgSubMembers(&schema.Message{
+		Sender: "Leader", Receiver: "Writer", Metadata: map[string]any{"amount": 10},
+	}))
+	assert.Empty(t, team.GetMsgSubMembers(&schema.Message{
+		Sender: "Auditor", Metadata: map[string]any{"amount": 5000},
+	}))
+	assert.Empty(t, team.GetSubMembers(context.Background(), leader))
+
+	// two predicates of a subscriber are both kept by InitSubRelation
+	fraud, err := CompileCondition(`"fraud" in content`)
+	require.NoError(t, err)
+	team.Subscribes = append(team.Subscribes, schema.Subscribe{
+		Subscriber: auditor,
+		Predicate:  fraud,
+	})
+	team.SubMode = CustomSubMode
+	require.NoError(t, team.InitSubRelation())
+	assert.Equal(t, []string{"Auditor"}, team.GetMsgSubMembers(&schema.Message{
+		Sender: "Leader", Receiver: "Writer", Content: "fraud suspected",
+	}))
+	assert.Equal(t, []string{"Auditor"}, team.GetMsgSubMembers(&schema.Message{
+		Sender: "Leader", Receiver: "Writer", Metadata: map[string]any{"amount": 5000},
+	}))
+}
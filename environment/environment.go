@@ -2,17 +2,37 @@ package environment
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
 
+	"github.com/antgroup/aievo/callback"
+	"github.com/antgroup/aievo/memory"
 	"github.com/antgroup/aievo/schema"
-	"github.com/thoas/go-funk"
 )
 
+var (
+	ErrMaxTurnsExceeded  = errors.New("max turns exceeded")
+	ErrMaxTokensExceeded = errors.New("max tokens exceeded")
+	ErrInjectType        = errors.New("only messages can be injected")
+	ErrUnknownMsgType    = errors.New("unknown message type")
+)
+
+// Produce dispatches msgs to the strategies of their types. A message of a
+// type without strategy is skipped, and told to the callback when it is a
+// callback.ErrorHandler. The strategies, the callbacks and the visibility
+// policy are called without the lock of e, so they may call e back.
 func (e *Environment) Produce(ctx context.Context, msgs ...schema.Message) error {
 	for _, msg := range msgs {
 		msg.Type = strings.ToUpper(msg.Type)
-		e.token += msg.Token
+		e.addToken(msg.Token)
 		err := e.dispatch(ctx, &msg)
+		if errors.Is(err, ErrUnknownMsgType) {
+			if handler, ok := e.Callback.(callback.ErrorHandler); ok {
+				handler.HandleError(ctx, err)
+			}
+			continue
+		}
 		if err != nil {
 			return err
 		}
@@ -20,15 +40,45 @@ func (e *Environment) Produce(ctx context.Context, msgs ...schema.Message) error
 	return nil
 }
 
+// Inject puts msgs ahead of the messages not consumed yet, so the next
+// Consume returns them, e.g. to steer a running team. When the memory is
+// not a schema.PriorityMemory, they are queued as by Produce.
+func (e *Environment) Inject(ctx context.Context, msgs ...schema.Message) error {
+	for _, msg := range msgs {
+		if !msg.IsMsg() {
+			return fmt.Errorf("%w: %s", ErrInjectType, msg.Type)
+		}
+	}
+	pm, ok := e.Memory.(schema.PriorityMemory)
+	if !ok {
+		return e.Produce(ctx, msgs...)
+	}
+	injected := make([]schema.Message, 0, len(msgs))
+	for _, msg := range msgs {
+		msg.Type = strings.ToUpper(msg.Type)
+		e.addToken(msg.Token)
+		if e.Callback != nil {
+			e.Callback.HandleMessageInQueue(ctx, &msg)
+		}
+		e.setReceivers(ctx, &msg)
+		injected = append(injected, msg)
+	}
+	return pm.SaveNext(ctx, injected...)
+}
+
 // Consume When reach max token or max turn, consume return nil
 // Consume will return next message unhandled,
 // when next message is same receiver, it will be return instead of next message
 func (e *Environment) Consume(ctx context.Context) *schema.Message {
-	if (e.MaxTurn > 0 && e.turn > e.MaxTurn) ||
-		(e.MaxToken > 0 && e.token > e.MaxToken) {
+	e.mu.Lock()
+	if e.exceeded() != nil {
+		e.mu.Unlock()
 		return nil
 	}
 	e.turn++
+	e.mu.Unlock()
+	// the memory tells the schedulable messages by the types of e
+	ctx = schema.ContextWithEnv(ctx, e)
 	// 合并相同receiver的消息
 	msg := e.Memory.LoadNext(ctx, nil)
 	for {
@@ -46,19 +96,163 @@ func (e *Environment) Consume(ctx context.Context) *schema.Message {
 	return msg
 }
 
+// Reset clears the memory and the turn and token counters, so the
+// environment can serve a fresh run.
+func (e *Environment) Reset(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.turn = 0
+	e.token = 0
+	return e.Memory.Clear(ctx)
+}
+
+// ResetCounters resets the turn and token counters but keeps the memory,
+// so a conversation can go on with a fresh budget.
+func (e *Environment) ResetCounters() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.turn = 0
+	e.token = 0
+}
+
+// Exceeded returns ErrMaxTurnsExceeded or ErrMaxTokensExceeded when the
+// limit is exceeded, and Consume returns nil.
+func (e *Environment) Exceeded() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.exceeded()
+}
+
+func (e *Environment) exceeded() error {
+	if e.MaxTurn > 0 && e.turn > e.MaxTurn {
+		return ErrMaxTurnsExceeded
+	}
+	if e.MaxToken > 0 && e.token > e.MaxToken {
+		return ErrMaxTokensExceeded
+	}
+	return nil
+}
+
+// Turn returns the number of turns consumed so far.
+func (e *Environment) Turn() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.turn
+}
+
+func (e *Environment) addToken(token int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.token += token
+}
+
+// Token returns the number of tokens consumed so far.
+func (e *Environment) Token() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.token
+}
+
+// LoadMemory returns the messages receiver sees, as decided by the
+// visibility policy. The observers see the messages not consumed yet.
 func (e *Environment) LoadMemory(ctx context.Context, receiver schema.Agent) []schema.Message {
-	// 按照当前消费位点，返回消息
-	if receiver == nil || receiver == e.Watcher || receiver == e.SopExpert ||
-		receiver == e.Planner {
+	if receiver == nil {
 		return e.Memory.Load(ctx, nil)
 	}
-	return e.Memory.Load(ctx, func(index, consumption int, message schema.Message) bool {
-		if index <= consumption && (strings.EqualFold(message.Sender, receiver.Name()) ||
-			funk.ContainsString(message.AllReceiver, receiver.Name())) {
-			return true
+	// 按照当前消费位点，返回消息
+	observer := e.IsObserver(receiver)
+	messages := e.Memory.Load(ctx, func(index, consumption int, message schema.Message) bool {
+		if !observer && index > consumption {
+			return false
 		}
-		return false
+		_, ok := e.View(ctx, receiver, message)
+		return ok
 	})
+	for i := range messages {
+		messages[i], _ = e.View(ctx, receiver, messages[i])
+	}
+	return messages
+}
+
+// View returns what viewer sees of msg, and whether it sees it at all, as
+// decided by the visibility policy.
+func (e *Environment) View(ctx context.Context, viewer schema.Agent, msg schema.Message) (schema.Message, bool) {
+	return e.visibility().View(ctx, e, viewer, msg)
+}
+
+// IsObserver reports whether agent observes the team rather than being a
+// member, i.e. is the watcher, the SOP expert or the planner.
+func (e *Environment) IsObserver(agent schema.Agent) bool {
+	return agent != nil && (agent == e.Watcher || agent == e.SopExpert || agent == e.Planner)
+}
+
+func (e *Environment) visibility() VisibilityPolicy {
+	if e.Visibility == nil {
+		return _defaultVisibility
+	}
+	return e.Visibility
+}
+
+// Join adds agent to the team, see Team.Join. The agent runs in e unless
+// it has an environment already.
+func (e *Environment) Join(ctx context.Context, agent schema.Agent, subs ...schema.Subscribe) error {
+	if err := e.Team.Join(agent, subs...); err != nil {
+		return err
+	}
+	if agent.Env() == nil {
+		agent.WithEnv(e)
+	}
+	if handler, ok := e.Callback.(callback.TeamHandler); ok {
+		handler.HandleMemberJoin(ctx, agent)
+	}
+	return nil
+}
+
+// Leave removes the member called name from the team, see Team.Leave.
+func (e *Environment) Leave(ctx context.Context, name string) error {
+	agent, err := e.Team.Leave(name)
+	if err != nil {
+		return err
+	}
+	if handler, ok := e.Callback.(callback.TeamHandler); ok {
+		handler.HandleMemberLeave(ctx, agent)
+	}
+	return nil
+}
+
+// Replace puts agent in place of the member called old, see Team.Replace.
+func (e *Environment) Replace(ctx context.Context, old string, agent schema.Agent) error {
+	replaced, err := e.Team.Replace(old, agent)
+	if err != nil {
+		return err
+	}
+	if agent.Env() == nil {
+		agent.WithEnv(e)
+	}
+	if handler, ok := e.Callback.(callback.TeamHandler); ok {
+		handler.HandleMemberReplace(ctx, replaced, agent)
+	}
+	return nil
+}
+
+var _ schema.AgentStateEnvironment = (*Environment)(nil)
+
+// AgentState returns the state agent keeps in e, nil when none.
+func (e *Environment) AgentState(agent schema.Agent) any {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.states[agent]
+}
+
+// SetAgentState sets the state agent keeps in e, e.g. its progress on the
+// SOP.
+func (e *Environment) SetAgentState(agent schema.Agent, state any) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.states == nil {
+		e.states = make(map[schema.Agent]any)
+	}
+	e.states[agent] = state
 }
 
 func (e *Environment) Agent(name string) schema.Agent {
@@ -76,13 +270,119 @@ func (e *Environment) GetSubscribeAgents(ctx context.Context,
 }
 
 func (e *Environment) SOP() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	return e.Sop
 }
 
+func (e *Environment) GetBlackboard() schema.Blackboard {
+	if e.Blackboard == nil {
+		return nil
+	}
+	return e.Blackboard
+}
+
 func (e *Environment) GetTeam() []schema.Agent {
-	return e.Team.members
+	return e.Team.Members()
 }
 
 func (e *Environment) GetTeamLeader() schema.Agent {
 	return e.Team.Leader
 }
+
+// State is the serializable state of an environment during a run.
+type State struct {
+	Messages []schema.Message `json:"messages"`
+	// Consumption is the number of messages consumed
+	Consumption int    `json:"consumption"`
+	Turn        int    `json:"turn"`
+	Token       int    `json:"token"`
+	SOP         string `json:"sop,omitempty"`
+	// Members are the names of the members, in order
+	Members    []string                 `json:"members"`
+	Blackboard []schema.BlackboardEntry `json:"blackboard,omitempty"`
+}
+
+// Snapshot returns the state of e, its memory must be a
+// schema.SnapshotMemory.
+func (e *Environment) Snapshot(ctx context.Context) (*State, error) {
+	sm, ok := e.Memory.(schema.SnapshotMemory)
+	if !ok {
+		return nil, memory.ErrSnapshotUnsupported
+	}
+	// the memory is user code, so it is not called under e.mu
+	messages, consumption, err := sm.Snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	state := &State{
+		Messages:    messages,
+		Consumption: consumption,
+	}
+	e.mu.Lock()
+	state.Turn, state.Token, state.SOP = e.turn, e.token, e.Sop
+	e.mu.Unlock()
+	for _, member := range e.Team.Members() {
+		state.Members = append(state.Members, member.Name())
+	}
+	if e.Blackboard != nil {
+		state.Blackboard = e.Blackboard.List()
+	}
+	return state, nil
+}
+
+// Restore puts e back in state. The agents cannot be serialized, so the
+// team must have all the members of state, the others are removed, e.g.
+// the ones removed by the watcher.
+func (e *Environment) Restore(ctx context.Context, state *State) error {
+	sm, ok := e.Memory.(schema.SnapshotMemory)
+	if !ok {
+		return memory.ErrSnapshotUnsupported
+	}
+	keep := make(map[string]bool, len(state.Members))
+	for _, name := range state.Members {
+		if e.Team.Member(name) == nil {
+			return fmt.Errorf("%w: %s", ErrMissingMember, name)
+		}
+		keep[strings.ToLower(name)] = true
+	}
+	for _, member := range e.Team.Members() {
+		if keep[strings.ToLower(member.Name())] {
+			continue
+		}
+		if _, err := e.Team.Leave(member.Name()); err != nil {
+			return err
+		}
+	}
+
+	if err := sm.Restore(ctx, state.Messages, state.Consumption); err != nil {
+		return err
+	}
+	e.mu.Lock()
+	e.turn, e.token, e.Sop = state.Turn, state.Token, state.SOP
+	e.mu.Unlock()
+	if e.Blackboard != nil {
+		e.Blackboard.restore(state.Blackboard)
+	}
+	return nil
+}
+
+// Rewind replaces the memory with messages as if they were produced, the
+// first consumption of them being consumed, e.g. to fork a run. The
+// receivers of the messages are computed when missing, e.g. for messages
+// read from an event log.
+func (e *Environment) Rewind(ctx context.Context, messages []schema.Message, consumption int) error {
+	sm, ok := e.Memory.(schema.SnapshotMemory)
+	if !ok {
+		return memory.ErrSnapshotUnsupported
+	}
+	rewound := make([]schema.Message, len(messages))
+	for i, msg := range messages {
+		msg.Type = strings.ToUpper(msg.Type)
+		if len(msg.AllReceiver) == 0 {
+			e.setReceivers(ctx, &msg)
+		}
+		rewound[i] = msg
+	}
+	return sm.Restore(ctx, rewound, consumption)
+}
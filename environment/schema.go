@@ -2,6 +2,8 @@ package environment
 
 import (
 	"context"
+	"sync"
+	"sync/atomic"
 
 	"github.com/antgroup/aievo/callback"
 	"github.com/antgroup/aievo/memory"
@@ -21,23 +23,91 @@ type Environment struct {
 	MaxTurn        int
 	MaxToken       int
 	Sop            string
+	// Blackboard is the state shared by the agents, besides the messages
+	Blackboard *Blackboard
+	// Visibility decides what the agents see of the memory, a Visibility
+	// without roles nor redactions by default
+	Visibility VisibilityPolicy
 
 	strategies map[string]func(context.Context, *schema.Message) error
+	// custom are the strategies registered, msgTypes the custom message
+	// types, both kept by Clone. msgTypes is replaced on each register, so
+	// the memories read it without a lock while they hold their own
+	custom   map[string]Strategy
+	msgTypes atomic.Pointer[map[string]schema.MsgType]
+	// states are the states of the agents running in e
+	states map[schema.Agent]any
 
 	turn  int
 	token int
+	// mu guards the counters, the SOP, the strategies, the writes of the
+	// message types and the states. It is never held
+	// while calling user code, e.g. a callback, which may call e back
+	mu sync.Mutex
 }
 
 func NewEnv() *Environment {
 	e := &Environment{
-		Team:   NewTeam(),
-		Memory: memory.NewBufferMemory(),
+		Team:       NewTeam(),
+		Memory:     memory.NewBufferMemory(),
+		Blackboard: NewBlackboard(),
 	}
+	e.initStrategies()
+	e.initBlackboard()
+	return e
+}
+
+// Clone returns an environment with the same configuration and team as e,
+// but with mem as memory, fresh counters, an empty blackboard and no agent
+// state. mem defaults to a new buffer memory when nil.
+func (e *Environment) Clone(mem schema.Memory) *Environment {
+	if mem == nil {
+		mem = memory.NewBufferMemory()
+	}
+	c := &Environment{
+		SopExpert:      e.SopExpert,
+		Planner:        e.Planner,
+		Watcher:        e.Watcher,
+		WatchCondition: e.WatchCondition,
+		Memory:         mem,
+		Callback:       e.Callback,
+		MaxTurn:        e.MaxTurn,
+		MaxToken:       e.MaxToken,
+		Sop:            e.Sop,
+		Blackboard:     NewBlackboard(),
+		Visibility:     e.Visibility,
+		custom:         make(map[string]Strategy, len(e.custom)),
+	}
+	for msgType, strategy := range e.custom {
+		c.custom[msgType] = strategy
+	}
+	// the types are never changed in place, so they can be shared
+	c.msgTypes.Store(e.msgTypes.Load())
+	if e.Team != nil {
+		c.Team = e.Team.Clone()
+	}
+	c.initStrategies()
+	c.initBlackboard()
+	return c
+}
+
+// initBlackboard tells the writes to the blackboard to the callback.
+func (e *Environment) initBlackboard() {
+	if e.Blackboard == nil {
+		return
+	}
+	e.Blackboard.OnChange(func(ctx context.Context, old, new *schema.BlackboardEntry) {
+		if handler, ok := e.Callback.(callback.BlackboardHandler); ok {
+			handler.HandleBlackboardChange(ctx, old, new)
+		}
+	})
+}
+
+func (e *Environment) initStrategies() {
 	e.strategies = map[string]func(ctx context.Context, msg *schema.Message) error{
 		schema.MsgTypeMsg:      e.msgStrategy,
 		schema.MsgTypeEnd:      e.msgStrategy,
 		schema.MsgTypeSOP:      e.sopStrategy,
 		schema.MsgTypeCreative: e.mngInfoStrategy,
 	}
-	return e
 }
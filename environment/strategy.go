@@ -2,47 +2,171 @@ package environment
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
+	"github.com/antgroup/aievo/callback"
 	"github.com/antgroup/aievo/schema"
 	"github.com/thoas/go-funk"
 )
 
+// Strategy handles the messages of a type produced in e, e.g. tallies the
+// VOTE ones. It calls Deliver for the message to reach its receivers, and
+// may Produce other messages, e.g. the result of a tally.
+type Strategy func(ctx context.Context, e *Environment, msg *schema.Message) error
+
+// RegisterStrategy sets the strategy of the messages of msgType, in place
+// of the built-in one for MSG, END, SOP and CREATIVE. The strategies are
+// kept by Clone. Mark the type schedulable with RegisterMsgType for the
+// memory to deliver its messages, a schedulable type without a strategy
+// being delivered as MSG.
+func (e *Environment) RegisterStrategy(msgType string, strategy Strategy) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.custom == nil {
+		e.custom = make(map[string]Strategy)
+	}
+	e.custom[strings.ToUpper(strings.TrimSpace(msgType))] = strategy
+}
+
+var _ schema.MsgTypeEnvironment = (*Environment)(nil)
+
+// RegisterMsgType registers the custom message types of e, for the memory
+// to deliver the schedulable ones and the agents to accept them. A type
+// replaces the one of the same name, the types are kept by Clone.
+func (e *Environment) RegisterMsgType(types ...schema.MsgType) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	msgTypes := make(map[string]schema.MsgType)
+	if old := e.msgTypes.Load(); old != nil {
+		for name, t := range *old {
+			msgTypes[name] = t
+		}
+	}
+	for _, t := range types {
+		t.Name = strings.ToUpper(strings.TrimSpace(t.Name))
+		msgTypes[t.Name] = t
+	}
+	e.msgTypes.Store(&msgTypes)
+}
+
+// MsgType returns the custom message type of the name registered in e. It
+// takes no lock, the memories calling it while holding theirs.
+func (e *Environment) MsgType(name string) (schema.MsgType, bool) {
+	msgTypes := e.msgTypes.Load()
+	if msgTypes == nil {
+		return schema.MsgType{}, false
+	}
+	t, ok := (*msgTypes)[strings.ToUpper(strings.TrimSpace(name))]
+	return t, ok
+}
+
+// Deliver saves msg to the memory for its receivers and the subscribers
+// of its sender, as for a MSG.
+func (e *Environment) Deliver(ctx context.Context, msg *schema.Message) error {
+	return e.msgStrategy(ctx, msg)
+}
+
 // msg dispatch
 func (e *Environment) dispatch(ctx context.Context, msg *schema.Message) error {
-	e.Callback.HandleMessageInQueue(ctx, msg)
+	if e.Callback != nil {
+		e.Callback.HandleMessageInQueue(ctx, msg)
+	}
+	msgType, known := e.MsgType(msg.Type)
+	if !known && msg.IsCustom() {
+		msgType, known = e.declaredMsgType(msg)
+	}
+	e.mu.Lock()
+	strategy, exists := e.custom[msg.Type]
+	e.mu.Unlock()
+	if exists {
+		return strategy(ctx, e, msg)
+	}
 	if handler, exists := e.strategies[msg.Type]; exists {
 		return handler(ctx, msg)
 	}
-	return nil
+	// a schedulable type is delivered as MSG by default
+	if known && msgType.Schedulable {
+		return e.msgStrategy(ctx, msg)
+	}
+	return fmt.Errorf("%w: %s", ErrUnknownMsgType, msg.Type)
+}
+
+// declaredMsgType registers the type of msg in e when its sender declares
+// it, e.g. with agent.WithMsgTypes, so its messages are handled as the ones
+// of the types registered with RegisterMsgType.
+func (e *Environment) declaredMsgType(msg *schema.Message) (schema.MsgType, bool) {
+	sender, ok := e.Agent(msg.Sender).(schema.MsgTypeAgent)
+	if !ok {
+		return schema.MsgType{}, false
+	}
+	for _, t := range sender.MsgTypes() {
+		if strings.EqualFold(strings.TrimSpace(t.Name), msg.Type) {
+			e.RegisterMsgType(t)
+			return e.MsgType(msg.Type)
+		}
+	}
+	return schema.MsgType{}, false
 }
 
 func (e *Environment) msgStrategy(ctx context.Context, msg *schema.Message) error {
+	e.setReceivers(ctx, msg)
+	return e.Memory.Save(ctx, *msg)
+}
 
+// setReceivers fills the agents able to see msg, the receiver and the
+// subscribers of the sender, but the ones the visibility policy hides it
+// from, e.g. for a private message.
+func (e *Environment) setReceivers(ctx context.Context, msg *schema.Message) {
 	subscribers := e.Team.GetMsgSubMembers(msg)
 	if msg.Receiver != "" && e.Agent(msg.Receiver) != nil {
 		msg.AllReceiver = append(msg.AllReceiver, msg.Receiver)
 	}
-	if msg.IsMsg() {
+	if msg.IsMsg() || msg.IsCustom() {
 		msg.AllReceiver = funk.UniqString(
 			append(msg.AllReceiver, subscribers...))
 	}
-	return e.Memory.Save(ctx, *msg)
+	if msg.Visibility == schema.VisibilityTeam {
+		return
+	}
+	receivers := msg.AllReceiver[:0]
+	for _, name := range msg.AllReceiver {
+		agent := e.Agent(name)
+		if agent == nil {
+			continue
+		}
+		if _, ok := e.View(ctx, agent, *msg); ok {
+			receivers = append(receivers, name)
+		}
+	}
+	msg.AllReceiver = receivers
 }
 
 func (e *Environment) mngInfoStrategy(ctx context.Context, msg *schema.Message) error {
 	if msg.MngInfo == nil {
 		return nil
 	}
-	// only support 'Remove' currently
-	if msg.MngInfo.Remove != nil {
-		e.Team.RemoveMembers(msg.MngInfo.Remove)
+	// only support 'Remove' currently, a member which cannot leave, e.g.
+	// the leader, is told to the callback and stays
+	for _, name := range msg.MngInfo.Remove {
+		err := e.Leave(ctx, strings.TrimSpace(name))
+		if err == nil {
+			continue
+		}
+		if handler, ok := e.Callback.(callback.ErrorHandler); ok {
+			handler.HandleError(ctx, fmt.Errorf("remove %s: %w", name, err))
+		}
 	}
 	_ = e.Memory.Save(ctx, *msg)
 	return nil
 }
 
 func (e *Environment) sopStrategy(ctx context.Context, msg *schema.Message) error {
+	e.mu.Lock()
 	e.Sop = msg.Content
-	e.Callback.HandleSOP(ctx, e.Sop)
+	e.mu.Unlock()
+	if e.Callback != nil {
+		e.Callback.HandleSOP(ctx, msg.Content)
+	}
 	return nil
 }
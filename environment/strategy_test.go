@@ -0,0 +1,143 @@
+package environment
+
+import (
+	"context"
+	"testing"
+
+	"github.com/antgroup/aievo/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterStrategy(t *testing.T) {
+	ctx := context.Background()
+	alice, bob := namedAgent("alice"), namedAgent("bob")
+	env := NewEnv()
+	env.Team.AddMembers(alice, bob)
+	events := &teamEvents{}
+	env.Callback = events
+	env.RegisterMsgType(
+		schema.MsgType{Name: "vote", Description: "vote for a proposal", Schedulable: true},
+		schema.MsgType{Name: "ESCALATE", Description: "escalate to a human"})
+
+	votes, escalated := 0, make([]string, 0)
+	env.RegisterStrategy("Vote", func(ctx context.Context, e *Environment, msg *schema.Message) error {
+		votes++
+		return e.Deliver(ctx, msg)
+	})
+	env.RegisterStrategy("ESCALATE", func(ctx context.Context, e *Environment, msg *schema.Message) error {
+		escalated = append(escalated, msg.Content)
+		return nil
+	})
+
+	err := env.Produce(ctx,
+		schema.Message{Type: "vote", Sender: "alice", Receiver: "bob", Content: "yes"},
+		schema.Message{Type: "unknown", Sender: "alice", Receiver: "bob", Content: "dropped"},
+		schema.Message{Type: "escalate", Sender: "bob", Content: "help"},
+		schema.Message{Type: schema.MsgTypeMsg, Sender: "bob", Receiver: "alice", Content: "ok"},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"error " + ErrUnknownMsgType.Error() + ": UNKNOWN"}, events.events)
+	assert.Equal(t, 1, votes)
+	assert.Equal(t, []string{"help"}, escalated)
+
+	// the schedulable custom messages are delivered as the MSG ones
+	msg := env.Consume(ctx)
+	require.NotNil(t, msg)
+	assert.Equal(t, "VOTE", msg.Type)
+	assert.Equal(t, []string{"bob"}, msg.AllReceiver)
+	msg = env.Consume(ctx)
+	require.NotNil(t, msg)
+	assert.Equal(t, "ok", msg.Content)
+	assert.Nil(t, env.Consume(ctx))
+
+	// the strategies are kept by the clones, and given the clone
+	clone := env.Clone(nil)
+	require.NoError(t, clone.Produce(ctx,
+		schema.Message{Type: "VOTE", Sender: "bob", Receiver: "alice", Content: "no"}))
+	assert.Equal(t, 2, votes)
+	assert.Len(t, clone.Memory.Load(ctx, nil), 1)
+	assert.Len(t, env.Memory.Load(ctx, nil), 2)
+	_, ok := clone.MsgType("Vote")
+	assert.True(t, ok)
+
+	// the types are registered in env only
+	_, ok = NewEnv().MsgType("VOTE")
+	assert.False(t, ok)
+
+	// the memories read the types while holding their lock, which the
+	// checkpoints take after the one of env
+	env.mu.Lock()
+	_, ok = env.MsgType("VOTE")
+	env.mu.Unlock()
+	assert.True(t, ok)
+}
+
+func TestProduceReentry(t *testing.T) {
+	ctx := context.Background()
+	alice, bob := namedAgent("alice"), namedAgent("bob")
+	env := NewEnv()
+	env.Team.AddMembers(alice, bob)
+	// the strategies and the policy call the environment back
+	env.Visibility = VisibilityFunc(func(ctx context.Context, e *Environment,
+		viewer schema.Agent, msg schema.Message) (schema.Message, bool) {
+		_ = e.Token()
+		return _defaultVisibility.View(ctx, e, viewer, msg)
+	})
+	env.RegisterStrategy("ECHO", func(ctx context.Context, e *Environment, msg *schema.Message) error {
+		return e.Produce(ctx, schema.Message{Type: schema.MsgTypeMsg, Sender: msg.Sender,
+			Receiver: msg.Receiver, Content: msg.Content + " " + msg.Content, Token: e.Turn() + 1})
+	})
+
+	require.NoError(t, env.Produce(ctx,
+		schema.Message{Type: "echo", Sender: "alice", Receiver: "bob", Content: "hello", Token: 2}))
+	require.NoError(t, env.Inject(ctx,
+		schema.Message{Type: schema.MsgTypeMsg, Sender: "bob", Receiver: "alice", Content: "stop"}))
+	assert.Equal(t, 3, env.Token())
+	msg := env.Consume(ctx)
+	require.NotNil(t, msg)
+	assert.Equal(t, "stop", msg.Content)
+	msg = env.Consume(ctx)
+	require.NotNil(t, msg)
+	assert.Equal(t, "hello hello", msg.Content)
+}
+
+// typedAgent declares the custom message types it may send.
+type typedAgent struct {
+	namedAgent
+	types []schema.MsgType
+}
+
+func (a *typedAgent) MsgTypes() []schema.MsgType { return a.types }
+
+func TestDefaultMsgStrategy(t *testing.T) {
+	ctx := context.Background()
+	alice := &typedAgent{namedAgent: "alice", types: []schema.MsgType{
+		{Name: "artifact", Description: "share a file", Schedulable: true}}}
+	bob := namedAgent("bob")
+	env := NewEnv()
+	env.Team.AddMembers(alice, bob)
+	events := &teamEvents{}
+	env.Callback = events
+	env.RegisterMsgType(schema.MsgType{Name: "NOTE", Schedulable: true},
+		schema.MsgType{Name: "AUDIT"})
+
+	// the schedulable types without a strategy, registered or declared by
+	// their sender, are delivered as MSG
+	require.NoError(t, env.Produce(ctx,
+		schema.Message{Type: "note", Sender: "bob", Receiver: "alice", Content: "noted"},
+		schema.Message{Type: "artifact", Sender: "alice", Receiver: "bob", Content: "report.md"},
+		schema.Message{Type: "audit", Sender: "bob", Content: "no strategy"},
+	))
+	assert.Equal(t, []string{"error " + ErrUnknownMsgType.Error() + ": AUDIT"}, events.events)
+	msg := env.Consume(ctx)
+	require.NotNil(t, msg)
+	assert.Equal(t, "noted", msg.Content)
+	msg = env.Consume(ctx)
+	require.NotNil(t, msg)
+	assert.Equal(t, "report.md", msg.Content)
+	assert.Equal(t, []string{"bob"}, msg.AllReceiver)
+	assert.Nil(t, env.Consume(ctx))
+	_, ok := env.MsgType("ARTIFACT")
+	assert.True(t, ok)
+}
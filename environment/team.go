@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/antgroup/aievo/schema"
 )
@@ -12,7 +13,10 @@ import (
 type SubscribeMode int
 
 var (
-	ErrMissingLeader = errors.New("leader agent is not set")
+	ErrMissingLeader   = errors.New("leader agent is not set")
+	ErrMissingMember   = errors.New("member is not found")
+	ErrDuplicateMember = errors.New("member already exists")
+	ErrLeaderLeave     = errors.New("leader cannot leave the team")
 )
 
 const (
@@ -28,6 +32,12 @@ type Team struct {
 	Leader     schema.Agent
 	Subscribes []schema.Subscribe
 	SubMode    SubscribeMode
+
+	// explicit are the subscribes given by the user, the others are
+	// derived from the subscribe mode and rebuilt when members change
+	explicit    []schema.Subscribe
+	initialized bool
+	mu          sync.RWMutex
 }
 
 func NewTeam() *Team {
@@ -38,8 +48,20 @@ func NewTeam() *Team {
 	}
 }
 
+// InitSubRelation fills the subscribe relation via the subscribe mode. It
+// can be called again, the derived subscribes are rebuilt each time.
 func (t *Team) InitSubRelation() error {
-	// fill subscribe relation via subscribe mode
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.initialized {
+		t.explicit = append([]schema.Subscribe{}, t.Subscribes...)
+		t.initialized = true
+	}
+	return t.rebuild()
+}
+
+func (t *Team) rebuild() error {
+	t.Subscribes = append([]schema.Subscribe{}, t.explicit...)
 	switch t.SubMode {
 	case DefaultSubMode:
 		if t.Leader != nil {
@@ -48,7 +70,7 @@ func (t *Team) InitSubRelation() error {
 			t.buildAllSubRelation()
 		}
 	case LeaderSubMode:
-		if t.Leader != nil {
+		if t.Leader == nil {
 			return ErrMissingLeader
 		}
 		t.buildLeaderSubRelation()
@@ -60,7 +82,128 @@ func (t *Team) InitSubRelation() error {
 	return nil
 }
 
+// Join adds agent to the team with the subscribes given, and rebuilds the
+// derived subscribes so agent gets routes.
+func (t *Team) Join(agent schema.Agent, subs ...schema.Subscribe) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if agent == nil {
+		return ErrMissingMember
+	}
+	if t.member(agent.Name()) != nil {
+		return fmt.Errorf("%w: %s", ErrDuplicateMember, agent.Name())
+	}
+	t.members = append(t.members, agent)
+	if !t.initialized {
+		t.Subscribes = append(t.Subscribes, subs...)
+		return nil
+	}
+	t.explicit = append(t.explicit, subs...)
+	return t.rebuild()
+}
+
+// Leave removes the member called name and all its subscribes.
+func (t *Team) Leave(name string) (schema.Agent, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	agent := t.member(name)
+	if agent == nil {
+		return nil, fmt.Errorf("%w: %s", ErrMissingMember, name)
+	}
+	if t.Leader != nil && strings.EqualFold(t.Leader.Name(), agent.Name()) {
+		return nil, ErrLeaderLeave
+	}
+	t.removeMember(agent.Name())
+	if !t.initialized {
+		t.Subscribes = withoutAgent(t.Subscribes, agent.Name())
+		return agent, nil
+	}
+	t.explicit = withoutAgent(t.explicit, agent.Name())
+	return agent, t.rebuild()
+}
+
+// Replace puts agent in place of the member called old, agent takes over
+// its subscribes, and its leadership if any.
+func (t *Team) Replace(old string, agent schema.Agent) (schema.Agent, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if agent == nil {
+		return nil, ErrMissingMember
+	}
+	replaced := t.member(old)
+	if replaced == nil {
+		return nil, fmt.Errorf("%w: %s", ErrMissingMember, old)
+	}
+	if other := t.member(agent.Name()); other != nil && other != replaced {
+		return nil, fmt.Errorf("%w: %s", ErrDuplicateMember, agent.Name())
+	}
+	for i, member := range t.members {
+		if member == replaced {
+			t.members[i] = agent
+		}
+	}
+	if t.Leader != nil && strings.EqualFold(t.Leader.Name(), replaced.Name()) {
+		t.Leader = agent
+	}
+	if !t.initialized {
+		t.Subscribes = replaceAgent(t.Subscribes, replaced.Name(), agent)
+		return replaced, nil
+	}
+	t.explicit = replaceAgent(t.explicit, replaced.Name(), agent)
+	return replaced, t.rebuild()
+}
+
+func withoutAgent(subs []schema.Subscribe, name string) []schema.Subscribe {
+	kept := make([]schema.Subscribe, 0, len(subs))
+	for _, sub := range subs {
+		if isAgent(sub.Subscribed, name) || isAgent(sub.Subscriber, name) {
+			continue
+		}
+		kept = append(kept, sub)
+	}
+	return kept
+}
+
+func replaceAgent(subs []schema.Subscribe, name string, agent schema.Agent) []schema.Subscribe {
+	replaced := make([]schema.Subscribe, 0, len(subs))
+	for _, sub := range subs {
+		if isAgent(sub.Subscribed, name) {
+			sub.Subscribed = agent
+		}
+		if isAgent(sub.Subscriber, name) {
+			sub.Subscriber = agent
+		}
+		replaced = append(replaced, sub)
+	}
+	return replaced
+}
+
+func isAgent(agent schema.Agent, name string) bool {
+	return agent != nil && strings.EqualFold(agent.Name(), name)
+}
+
+// Clone returns a copy of t, so members can be removed from the copy
+// without affecting t.
+func (t *Team) Clone() *Team {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return &Team{
+		members:     append([]schema.Agent{}, t.members...),
+		Leader:      t.Leader,
+		Subscribes:  append([]schema.Subscribe{}, t.Subscribes...),
+		SubMode:     t.SubMode,
+		explicit:    append([]schema.Subscribe{}, t.explicit...),
+		initialized: t.initialized,
+	}
+}
+
 func (t *Team) Member(name string) schema.Agent {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.member(name)
+}
+
+func (t *Team) member(name string) schema.Agent {
 	for _, a := range t.members {
 		if strings.EqualFold(a.Name(), name) {
 			return a
@@ -69,7 +212,16 @@ func (t *Team) Member(name string) schema.Agent {
 	return nil
 }
 
+// Members returns a copy of the members.
+func (t *Team) Members() []schema.Agent {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return append([]schema.Agent{}, t.members...)
+}
+
 func (t *Team) AddMembers(members ...schema.Agent) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	for _, member := range members {
 		if member != nil {
 			t.members = append(t.members, member)
@@ -79,8 +231,13 @@ func (t *Team) AddMembers(members ...schema.Agent) {
 
 func (t *Team) GetSubMembers(_ context.Context,
 	subscribed schema.Agent) []schema.Agent {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
 	members := make([]schema.Agent, 0)
 	for _, subscribe := range t.Subscribes {
+		if subscribe.Subscribed == nil {
+			continue
+		}
 		if subscribe.Subscribed.Name() == subscribed.Name() &&
 			subscribe.Subscriber.Name() != subscribed.Name() {
 			// 仅考虑组内成员
@@ -96,11 +253,20 @@ func (t *Team) GetSubMembers(_ context.Context,
 }
 
 func (t *Team) GetMsgSubMembers(msg *schema.Message) (subscribers []string) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
 	for _, subscribe := range t.Subscribes {
-		if strings.EqualFold(subscribe.Subscribed.Name(), msg.Sender) {
+		if subscribe.Subscribed == nil ||
+			strings.EqualFold(subscribe.Subscribed.Name(), msg.Sender) {
 			if subscribe.Condition != "" && subscribe.Condition != msg.Condition {
 				continue
 			}
+			if subscribe.Predicate != nil && !subscribe.Predicate(*msg) {
+				continue
+			}
+			if subscribe.Subscriber.Name() == msg.Sender {
+				continue
+			}
 			for _, member := range t.members {
 				if subscribe.Subscriber.Name() == member.Name() {
 					subscribers = append(subscribers, subscribe.Subscriber.Name())
@@ -112,14 +278,22 @@ func (t *Team) GetMsgSubMembers(msg *schema.Message) (subscribers []string) {
 	return subscribers
 }
 
+// RemoveMembers removes the members called names, without changing the
+// subscribes, see Leave.
 func (t *Team) RemoveMembers(names []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	for _, name := range names {
-		for i, member := range t.members {
-			if strings.EqualFold(member.Name(),
-				strings.TrimSpace(name)) {
-				t.members = append(t.members[:i], t.members[i+1:]...)
-				break
-			}
+		t.removeMember(name)
+	}
+}
+
+func (t *Team) removeMember(name string) {
+	for i, member := range t.members {
+		if strings.EqualFold(member.Name(),
+			strings.TrimSpace(name)) {
+			t.members = append(t.members[:i:i], t.members[i+1:]...)
+			break
 		}
 	}
 }
@@ -162,6 +336,12 @@ func (t *Team) deduplicateSub() {
 		if sub.Subscribed == sub.Subscriber {
 			continue
 		}
+		// the predicates cannot be compared, e.g. the closures of
+		// CompileCondition all share a code pointer, so they are all kept
+		if sub.Predicate != nil {
+			subs = append(subs, sub)
+			continue
+		}
 		k := fmt.Sprintf("%s-%s-%s",
 			sub.Subscriber, sub.Subscribed, sub.Condition)
 		if _, ok := m[k]; !ok {
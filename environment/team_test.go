@@ -0,0 +1,109 @@
+package environment
+
+import (
+	"context"
+	"testing"
+
+	"github.com/antgroup/aievo/callback"
+	"github.com/antgroup/aievo/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type teamEvents struct {
+	callback.LogHandler
+	events []string
+}
+
+func (h *teamEvents) HandleMemberJoin(_ context.Context, a schema.Agent) {
+	h.events = append(h.events, "join "+a.Name())
+}
+
+func (h *teamEvents) HandleMemberLeave(_ context.Context, a schema.Agent) {
+	h.events = append(h.events, "leave "+a.Name())
+}
+
+func (h *teamEvents) HandleMemberReplace(_ context.Context, old, new schema.Agent) {
+	h.events = append(h.events, "replace "+old.Name()+" "+new.Name())
+}
+
+func (h *teamEvents) HandleError(_ context.Context, err error) {
+	h.events = append(h.events, "error "+err.Error())
+}
+
+func names(agents []schema.Agent) []string {
+	ns := make([]string, 0, len(agents))
+	for _, a := range agents {
+		ns = append(ns, a.Name())
+	}
+	return ns
+}
+
+func TestTeamJoinLeave(t *testing.T) {
+	ctx := context.Background()
+	p1, p2, p3 := namedAgent("p1"), namedAgent("p2"), namedAgent("p3")
+	events := &teamEvents{}
+	env := NewEnv()
+	env.Callback = events
+	env.Team.SubMode = ALLSubMode
+	env.Team.AddMembers(p1, p2)
+	require.NoError(t, env.Team.InitSubRelation())
+
+	require.NoError(t, env.Join(ctx, p3))
+	assert.ElementsMatch(t, []string{"p1", "p2"}, names(env.GetSubscribeAgents(ctx, p3)))
+	assert.ElementsMatch(t, []string{"p2", "p3"}, names(env.GetSubscribeAgents(ctx, p1)))
+	assert.ErrorIs(t, env.Join(ctx, p3), ErrDuplicateMember)
+
+	require.NoError(t, env.Leave(ctx, "P2"))
+	assert.Equal(t, []string{"p1", "p3"}, names(env.GetTeam()))
+	for _, sub := range env.Team.Subscribes {
+		assert.NotEqual(t, "p2", sub.Subscribed.Name())
+		assert.NotEqual(t, "p2", sub.Subscriber.Name())
+	}
+	assert.ErrorIs(t, env.Leave(ctx, "p2"), ErrMissingMember)
+	assert.Equal(t, []string{"join p3", "leave p2"}, events.events)
+}
+
+func TestTeamReplace(t *testing.T) {
+	ctx := context.Background()
+	leader, worker, auditor := namedAgent("leader"), namedAgent("worker"), namedAgent("auditor")
+	env := NewEnv()
+	env.Team.SubMode = LeaderSubMode
+	env.Team.Leader = leader
+	env.Team.AddMembers(leader, worker, auditor)
+	env.Team.Subscribes = append(env.Team.Subscribes, schema.Subscribe{
+		Subscribed: worker,
+		Subscriber: auditor,
+	})
+	require.NoError(t, env.Team.InitSubRelation())
+
+	worker2 := namedAgent("worker2")
+	require.NoError(t, env.Replace(ctx, "worker", worker2))
+	assert.Equal(t, []string{"leader", "worker2", "auditor"}, names(env.GetTeam()))
+	assert.ElementsMatch(t, []string{"leader", "auditor"}, names(env.GetSubscribeAgents(ctx, worker2)))
+	assert.ElementsMatch(t, []string{"worker2", "auditor"}, names(env.GetSubscribeAgents(ctx, leader)))
+	assert.Empty(t, env.GetSubscribeAgents(ctx, worker))
+
+	boss := namedAgent("boss")
+	require.NoError(t, env.Replace(ctx, "leader", boss))
+	assert.Equal(t, boss, env.GetTeamLeader())
+	assert.ErrorIs(t, env.Leave(ctx, "boss"), ErrLeaderLeave)
+
+	// mng info removes members through Leave
+	require.NoError(t, env.Produce(ctx, schema.Message{
+		Type:    schema.MsgTypeCreative,
+		MngInfo: &schema.MngInfo{Remove: []string{" auditor"}},
+	}))
+	assert.Equal(t, []string{"boss", "worker2"}, names(env.GetTeam()))
+	assert.ElementsMatch(t, []string{"boss"}, names(env.GetSubscribeAgents(ctx, worker2)))
+
+	// the leader cannot be removed, which is told to the callback
+	events := &teamEvents{}
+	env.Callback = events
+	require.NoError(t, env.Produce(ctx, schema.Message{
+		Type:    schema.MsgTypeCreative,
+		MngInfo: &schema.MngInfo{Remove: []string{"boss"}},
+	}))
+	assert.Equal(t, []string{"boss", "worker2"}, names(env.GetTeam()))
+	assert.Equal(t, []string{"error remove boss: " + ErrLeaderLeave.Error()}, events.events)
+}
@@ -0,0 +1,165 @@
+package environment
+
+import (
+	"context"
+	"strings"
+
+	"github.com/antgroup/aievo/schema"
+	"github.com/thoas/go-funk"
+)
+
+const _redacted = "[redacted]"
+
+// VisibilityPolicy decides what the agents see of the memory, it is
+// consulted by LoadMemory for each message. It is called without the lock
+// of the environment, so it may call it, e.g. Turn.
+type VisibilityPolicy interface {
+	// View returns what viewer sees of msg, and whether it sees it at all
+	View(ctx context.Context, e *Environment, viewer schema.Agent, msg schema.Message) (schema.Message, bool)
+}
+
+// VisibilityFunc is a function used as a VisibilityPolicy.
+type VisibilityFunc func(ctx context.Context, e *Environment, viewer schema.Agent, msg schema.Message) (schema.Message, bool)
+
+func (f VisibilityFunc) View(ctx context.Context, e *Environment, viewer schema.Agent, msg schema.Message) (schema.Message, bool) {
+	return f(ctx, e, viewer, msg)
+}
+
+// Redaction removes fields of the messages seen by some viewers.
+type Redaction struct {
+	// Fields are the fields removed: "thought", "content", "log",
+	// "metadata" or "metadata.<key>"
+	Fields []string
+	// Viewers are the names or the roles of the viewers concerned, all of
+	// them when empty. The sender always sees its messages whole
+	Viewers []string
+	// Match selects the messages concerned, all of them when nil
+	Match func(msg schema.Message) bool
+}
+
+// Visibility is the default VisibilityPolicy. The sender of a message and
+// the observers of the team, i.e. the watcher, the SOP expert and the
+// planner, see it, and the members according to Message.Visibility. The
+// fields of the redactions are then removed.
+type Visibility struct {
+	roles      map[string][]string
+	redactions []Redaction
+}
+
+var _ VisibilityPolicy = (*Visibility)(nil)
+
+var _defaultVisibility = NewVisibility()
+
+type VisibilityOption func(v *Visibility)
+
+// WithRoles gives roles to agent, for the role messages and the
+// redactions.
+func WithRoles(agent string, roles ...string) VisibilityOption {
+	return func(v *Visibility) {
+		v.roles[strings.ToLower(agent)] = append(v.roles[strings.ToLower(agent)], roles...)
+	}
+}
+
+func WithRedaction(redaction Redaction) VisibilityOption {
+	return func(v *Visibility) {
+		v.redactions = append(v.redactions, redaction)
+	}
+}
+
+func NewVisibility(opts ...VisibilityOption) *Visibility {
+	v := &Visibility{roles: make(map[string][]string)}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+func (v *Visibility) View(_ context.Context, e *Environment, viewer schema.Agent, msg schema.Message) (schema.Message, bool) {
+	if !v.visible(e, viewer, &msg) {
+		return msg, false
+	}
+	if strings.EqualFold(msg.Sender, viewer.Name()) {
+		return msg, true
+	}
+	for _, redaction := range v.redactions {
+		if redaction.Match != nil && !redaction.Match(msg) {
+			continue
+		}
+		if len(redaction.Viewers) != 0 && !v.is(viewer.Name(), redaction.Viewers) {
+			continue
+		}
+		msg = redact(msg, redaction.Fields)
+	}
+	return msg, true
+}
+
+func (v *Visibility) visible(e *Environment, viewer schema.Agent, msg *schema.Message) bool {
+	name := viewer.Name()
+	if strings.EqualFold(msg.Sender, name) || e.IsObserver(viewer) {
+		return true
+	}
+	switch strings.ToLower(msg.Visibility) {
+	case schema.VisibilityPublic:
+		return true
+	case schema.VisibilityPrivate:
+		return addressed(msg, name)
+	case schema.VisibilityRole:
+		return addressed(msg, name) || v.hasRole(name, msg.Roles)
+	default:
+		return funk.ContainsString(msg.AllReceiver, name)
+	}
+}
+
+// addressed reports whether msg is sent to name by name, not to ALL.
+func addressed(msg *schema.Message, name string) bool {
+	return !strings.EqualFold(msg.Receiver, schema.MsgAllReceiver) &&
+		containsFold(msg.Receivers(), name)
+}
+
+// is reports whether name is one of names, or has one of them as role.
+func (v *Visibility) is(name string, names []string) bool {
+	return containsFold(names, name) || v.hasRole(name, names)
+}
+
+func (v *Visibility) hasRole(name string, roles []string) bool {
+	for _, role := range v.roles[strings.ToLower(name)] {
+		if containsFold(roles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+func redact(msg schema.Message, fields []string) schema.Message {
+	for _, field := range fields {
+		switch field = strings.ToLower(field); {
+		case field == "thought":
+			msg.Thought = ""
+		case field == "content":
+			msg.Content = _redacted
+		case field == "log":
+			msg.Log = ""
+		case field == "metadata":
+			msg.Metadata = nil
+		case strings.HasPrefix(field, "metadata.") && msg.Metadata != nil:
+			// a copy, the message of the memory is not changed
+			metadata := make(map[string]any, len(msg.Metadata))
+			for k, value := range msg.Metadata {
+				if !strings.EqualFold(k, strings.TrimPrefix(field, "metadata.")) {
+					metadata[k] = value
+				}
+			}
+			msg.Metadata = metadata
+		}
+	}
+	return msg
+}
+
+func containsFold(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
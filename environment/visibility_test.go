@@ -0,0 +1,62 @@
+package environment
+
+import (
+	"context"
+	"testing"
+
+	"github.com/antgroup/aievo/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVisibility(t *testing.T) {
+	ctx := context.Background()
+	wolf1, wolf2, villager, judge := namedAgent("wolf1"), namedAgent("wolf2"), namedAgent("villager"), namedAgent("judge")
+	env := NewEnv()
+	env.Watcher = judge
+	env.Team.SubMode = ALLSubMode
+	env.Team.AddMembers(wolf1, wolf2, villager)
+	require.NoError(t, env.Team.InitSubRelation())
+	env.Visibility = NewVisibility(
+		WithRoles("wolf1", "werewolf"), WithRoles("wolf2", "werewolf"),
+		WithRedaction(Redaction{Fields: []string{"thought"}}),
+		WithRedaction(Redaction{Fields: []string{"metadata.seat"}, Viewers: []string{"werewolf"}}))
+
+	require.NoError(t, env.Produce(ctx,
+		schema.Message{Type: schema.MsgTypeMsg, Sender: "villager", Receiver: "ALL", Content: "good morning",
+			Thought: "wolf1 looks guilty", Metadata: map[string]any{"seat": 3}},
+		schema.Message{Type: schema.MsgTypeMsg, Sender: "wolf1", Receiver: "ALL", Content: "kill villager",
+			Visibility: schema.VisibilityRole, Roles: []string{"werewolf"}},
+		schema.Message{Type: schema.MsgTypeMsg, Sender: "wolf1", Receiver: "villager", Content: "trust me",
+			Visibility: schema.VisibilityPrivate},
+	))
+	for env.Consume(ctx) != nil {
+	}
+
+	contents := func(agent schema.Agent) []string {
+		result := make([]string, 0)
+		for _, msg := range env.LoadMemory(ctx, agent) {
+			result = append(result, msg.Content)
+		}
+		return result
+	}
+	assert.Equal(t, []string{"good morning", "kill villager", "trust me"}, contents(wolf1))
+	assert.Equal(t, []string{"good morning", "kill villager"}, contents(wolf2))
+	assert.Equal(t, []string{"good morning", "trust me"}, contents(villager))
+	assert.Equal(t, []string{"good morning", "kill villager", "trust me"}, contents(judge))
+
+	// only the members seeing the message are its receivers
+	messages := env.Memory.Load(ctx, nil)
+	assert.Equal(t, []string{"wolf2"}, messages[1].AllReceiver)
+	assert.Equal(t, []string{"villager"}, messages[2].AllReceiver)
+
+	seen := env.LoadMemory(ctx, wolf2)[0]
+	assert.Empty(t, seen.Thought)
+	assert.Empty(t, seen.Metadata)
+	seen = env.LoadMemory(ctx, judge)[0]
+	assert.Empty(t, seen.Thought)
+	assert.Equal(t, 3, seen.Metadata["seat"])
+	seen = env.LoadMemory(ctx, villager)[0]
+	assert.Equal(t, "wolf1 looks guilty", seen.Thought)
+	assert.Equal(t, 3, messages[0].Metadata["seat"])
+}
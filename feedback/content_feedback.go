@@ -14,7 +14,7 @@ func NewContentFeedback() Feedback {
 	return &ContentFeedback{}
 }
 
-func (*ContentFeedback) Feedback(_ context.Context, agent schema.Agent,
+func (*ContentFeedback) Feedback(ctx context.Context, agent schema.Agent,
 	messages []schema.Message, actions []schema.StepAction,
 	steps []schema.StepAction, _ string) *FeedbackInfo {
 	// 1. check response is correct
@@ -26,7 +26,7 @@ func (*ContentFeedback) Feedback(_ context.Context, agent schema.Agent,
 		return fd
 	}
 
-	if fd := checkMessages(agent, messages); fd != nil {
+	if fd := checkMessages(ctx, agent, messages); fd != nil {
 		return fd
 	}
 
@@ -61,9 +61,10 @@ func checkRepeatCall(steps []schema.StepAction, actions []schema.StepAction) *Fe
 	return nil
 }
 
-func checkMessages(agent schema.Agent, messages []schema.Message) *FeedbackInfo {
+func checkMessages(ctx context.Context, agent schema.Agent, messages []schema.Message) *FeedbackInfo {
 	for _, msg := range messages {
-		if !msg.IsMsg() {
+		// the custom messages delivered need a receiver as well
+		if !msg.IsMsg() && !(msg.IsCustom() && msg.IsSchedulable(schema.EnvOf(ctx, agent))) {
 			continue
 		}
 		if msg.Receiver == "" {
@@ -72,7 +73,7 @@ func checkMessages(agent schema.Agent, messages []schema.Message) *FeedbackInfo
 				Msg:  "receiver cannot be empty where message cate is not END",
 			}
 		}
-		if checkReceiver(agent, msg) {
+		if checkReceiver(ctx, agent, msg) {
 			continue
 		}
 		return &FeedbackInfo{
@@ -83,12 +84,12 @@ func checkMessages(agent schema.Agent, messages []schema.Message) *FeedbackInfo
 	return nil
 }
 
-func checkReceiver(agent schema.Agent, msg schema.Message) bool {
-	if agent.Env() == nil {
+func checkReceiver(ctx context.Context, agent schema.Agent, msg schema.Message) bool {
+	env := schema.EnvOf(ctx, agent)
+	if env == nil {
 		return true
 	}
-	agents := agent.Env().GetSubscribeAgents(
-		context.Background(), agent)
+	agents := env.GetSubscribeAgents(ctx, agent)
 	if len(agents) == 0 {
 		return true
 	}
@@ -3,6 +3,7 @@ package feedback
 import (
 	"context"
 	"strings"
+	"sync"
 	"sync/atomic"
 
 	"github.com/antgroup/aievo/schema"
@@ -51,6 +52,8 @@ type LLMFeedback struct {
 	expert           int
 	middlewaresChain Middleware
 	Vars             map[string]any
+	// serializes the feedbacks of an agent shared by several sessions
+	mu sync.Mutex
 }
 
 func NewLLMFeedback(LLM llm.LLM, opts ...LLMFeedbackOption) (Feedback, error) {
@@ -78,6 +81,8 @@ func NewLLMFeedback(LLM llm.LLM, opts ...LLMFeedbackOption) (Feedback, error) {
 func (lf *LLMFeedback) Feedback(ctx context.Context, agent schema.Agent,
 	messages []schema.Message, actions []schema.StepAction,
 	steps []schema.StepAction, prompt string) *FeedbackInfo {
+	lf.mu.Lock()
+	defer lf.mu.Unlock()
 	if lf.middlewaresChain != nil &&
 		!lf.middlewaresChain(ctx, lf, agent, messages, actions, steps, prompt) {
 		return &FeedbackInfo{Type: Approved}
@@ -104,13 +109,13 @@ func (lf *LLMFeedback) feedback(ctx context.Context, agent schema.Agent,
 	}
 
 	vars := make(map[string]any)
-	if agent.Env() != nil {
-		memory := agent.Env().LoadMemory(ctx, agent)
+	if env := schema.EnvOf(ctx, agent); env != nil {
+		memory := env.LoadMemory(ctx, agent)
 		vars["history"] = schema.ConvertConstructScratchPad(agent.Name(),
 			agent.Name(), memory, steps)
-		vars["sop"] = agent.Env().SOP()
-		vars["agent_names"] = schema.ConvertAgentNames(agent.Env().GetSubscribeAgents(ctx, agent))
-		vars["agent_descriptions"] = schema.ConvertAgentDescriptions(agent.Env().GetSubscribeAgents(ctx, agent))
+		vars["sop"] = env.SOP()
+		vars["agent_names"] = schema.ConvertAgentNames(env.GetSubscribeAgents(ctx, agent))
+		vars["agent_descriptions"] = schema.ConvertAgentDescriptions(env.GetSubscribeAgents(ctx, agent))
 	}
 
 	vars["tool_names"] = schema.ConvertToolNames(agent.Tools())
@@ -136,6 +141,7 @@ func (lf *LLMFeedback) feedback(ctx context.Context, agent schema.Agent,
 	}
 
 	approve := int32(0)
+	var mu sync.Mutex
 	parallel.Parallel(func(i int) any {
 		temperatures := []float32{0.1, 0.2, 0.3, 0.4, 0.5}
 		result, err := lf.llm.Generate(ctx, p,
@@ -144,9 +150,11 @@ func (lf *LLMFeedback) feedback(ctx context.Context, agent schema.Agent,
 			atomic.AddInt32(&approve, 1)
 			return nil
 		}
-		info.Token = result.Usage.TotalTokens
 		tmp := &FeedbackInfo{Type: Approved}
-		_ = json.Unmarshal([]byte(json.TrimJsonString(result.Content)), tmp)
+		_ = json.Unmarshal([]byte(json.RepairJsonString(result.Content)), tmp)
+		mu.Lock()
+		defer mu.Unlock()
+		info.Token = result.Usage.TotalTokens
 		if tmp == nil || tmp.Type == Approved {
 			atomic.AddInt32(&approve, 1)
 		} else {
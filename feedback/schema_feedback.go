@@ -0,0 +1,50 @@
+package feedback
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/antgroup/aievo/schema"
+	"github.com/antgroup/aievo/tool"
+	ujson "github.com/antgroup/aievo/utils/json"
+)
+
+// SchemaFeedback rejects final answers whose content does not match the
+// output schema.
+type SchemaFeedback struct {
+	schema *tool.PropertiesSchema
+}
+
+func NewSchemaFeedback(schema *tool.PropertiesSchema) Feedback {
+	return &SchemaFeedback{schema: schema}
+}
+
+func (sf *SchemaFeedback) Feedback(_ context.Context, _ schema.Agent,
+	messages []schema.Message, _ []schema.StepAction,
+	_ []schema.StepAction, _ string) *FeedbackInfo {
+	for _, msg := range messages {
+		if !msg.IsEnd() {
+			continue
+		}
+		if err := ValidateContent(sf.schema, msg.Content); err != nil {
+			return &FeedbackInfo{
+				Type: NotApproved,
+				Msg:  SchemaViolation(sf.schema, err),
+			}
+		}
+	}
+	return &FeedbackInfo{Type: Approved}
+}
+
+// ValidateContent validates content, after repairing it, against schema.
+func ValidateContent(schema *tool.PropertiesSchema, content string) error {
+	return schema.Validate([]byte(ujson.RepairJsonString(content)))
+}
+
+// SchemaViolation describes err to the agent along with the schema expected.
+func SchemaViolation(schema *tool.PropertiesSchema, err error) string {
+	bytes, _ := json.Marshal(schema)
+	return fmt.Sprintf("the content of final answer does not match the output schema:\n%s\n"+
+		"the content MUST be a json matching the schema: %s", err.Error(), string(bytes))
+}
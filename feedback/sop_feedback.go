@@ -91,7 +91,7 @@ func FillInSopCheckPrompt(ctx context.Context, lf *LLMFeedback,
 		sop = messages[0].Thought
 	}
 
-	memory := a.Env().LoadMemory(ctx, a)
+	memory := schema.EnvOf(ctx, a).LoadMemory(ctx, a)
 	history := schema.ConvertConstructScratchPad(a.Name(),
 		a.Name(), memory, steps)
 	lf.Vars = map[string]any{
@@ -93,8 +93,9 @@ func FillInStabilityPrompt(ctx context.Context, lf *LLMFeedback,
 	if len(messages) > 0 {
 		message, _ = json.Marshal(messages)
 	}
+	env := schema.EnvOf(ctx, a)
 	leader := ""
-	if leader == a.Env().GetTeamLeader().Name() {
+	if leader == env.GetTeamLeader().Name() {
 		leader = a.Name()
 	}
 
@@ -105,10 +106,10 @@ func FillInStabilityPrompt(ctx context.Context, lf *LLMFeedback,
 		"steps":             string(step),
 		"leader":            leader,
 		"agent_name":        a.Name(),
-		"sop":               a.Env().SOP(),
+		"sop":               env.SOP(),
 		"tool_descriptions": schema.ConvertToolDescriptions(a.Tools()),
 		"agent_descriptions": schema.ConvertAgentDescriptions(
-			a.Env().GetSubscribeAgents(ctx, a)),
+			env.GetSubscribeAgents(ctx, a)),
 		"history": schema.ConvertConstructScratchPad(a.Name(), a.Name(),
 			messages, steps),
 	}
@@ -2,14 +2,25 @@ package memory
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
 
 	"github.com/antgroup/aievo/schema"
 )
 
+var (
+	ErrInvalidSnapshot     = errors.New("invalid memory snapshot")
+	ErrSnapshotUnsupported = errors.New("memory does not support snapshots")
+)
+
+// Buffer keeps messages in memory, it is safe for concurrent use.
 type Buffer struct {
 	Messages []schema.Message
 	index    int
 	window   int
+
+	mu sync.RWMutex
 }
 
 func NewBufferMemory() *Buffer {
@@ -21,6 +32,8 @@ func NewBufferWindowMemory(window int) *Buffer {
 }
 
 func (c *Buffer) Load(ctx context.Context, filter func(index, consumption int, message schema.Message) bool) []schema.Message {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	msgs := make([]schema.Message, 0, len(c.Messages))
 	for i, message := range c.Messages {
 		if filter == nil || filter(i, c.index, message) {
@@ -34,18 +47,21 @@ func (c *Buffer) Load(ctx context.Context, filter func(index, consumption int, m
 }
 
 func (c *Buffer) LoadNext(ctx context.Context, filter func(message schema.Message) bool) *schema.Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	if c.index >= len(c.Messages) {
 		return nil
 	}
 	for ; c.index < len(c.Messages); c.index++ {
-		if c.Messages[c.index].IsMsg() || c.Messages[c.index].IsEnd() ||
-			c.Messages[c.index].IsCreative() {
+		if c.Messages[c.index].IsSchedulable(schema.EnvFromContext(ctx)) {
 			if c.Messages[c.index].Sender != c.Messages[c.index].Receiver {
 				if filter != nil && !filter(c.Messages[c.index]) {
 					return nil
 				}
 				c.index++
-				return &c.Messages[c.index-1]
+				// a copy, as the messages may be reallocated by Save
+				msg := c.Messages[c.index-1]
+				return &msg
 			}
 		}
 	}
@@ -53,11 +69,59 @@ func (c *Buffer) LoadNext(ctx context.Context, filter func(message schema.Messag
 }
 
 func (c *Buffer) Save(ctx context.Context, msg schema.Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.Messages = append(c.Messages, msg)
 	return nil
 }
 
+// SaveNext inserts msgs before the messages not consumed yet.
+func (c *Buffer) SaveNext(ctx context.Context, msgs ...schema.Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Messages = append(c.Messages[:c.index],
+		append(append([]schema.Message{}, msgs...), c.Messages[c.index:]...)...)
+	return nil
+}
+
+// Snapshot returns a copy of the messages and the consumption cursor.
+func (c *Buffer) Snapshot(ctx context.Context) ([]schema.Message, int, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]schema.Message{}, c.Messages...), c.index, nil
+}
+
+// Restore replaces the messages and the consumption cursor.
+func (c *Buffer) Restore(ctx context.Context, messages []schema.Message, consumption int) error {
+	if consumption < 0 || consumption > len(messages) {
+		return fmt.Errorf("%w: consumption %d of %d messages",
+			ErrInvalidSnapshot, consumption, len(messages))
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Messages = append([]schema.Message{}, messages...)
+	c.index = consumption
+	return nil
+}
+
 func (c *Buffer) Clear(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.Messages = c.Messages[:0]
+	c.index = 0
 	return nil
 }
+
+// replace replaces the messages, keeping the consumption index.
+func (c *Buffer) replace(messages []schema.Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Messages = append(c.Messages[:0], messages...)
+}
+
+// extend appends messages, keeping the consumption index.
+func (c *Buffer) extend(messages []schema.Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Messages = append(c.Messages, messages...)
+}
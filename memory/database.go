@@ -2,6 +2,9 @@ package memory
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
 
 	"github.com/antgroup/aievo/schema"
 )
@@ -9,11 +12,21 @@ import (
 type Database struct {
 	buffer *Buffer
 
-	window   int
-	saveFunc func(ctx context.Context, msg *schema.Message) error
-	loadFunc func(ctx context.Context) []schema.Message
+	window      int
+	saveFunc    func(ctx context.Context, msg *schema.Message) error
+	loadFunc    func(ctx context.Context) []schema.Message
+	incLoadFunc func(ctx context.Context, after int) []schema.Message
+	clearFunc   func(ctx context.Context) error
+
+	loadCursorFunc func(ctx context.Context) (int, error)
+	saveCursorFunc func(ctx context.Context, cursor int) error
+	cursorLoaded   bool
+
+	mu sync.Mutex
 }
 
+var ErrMissingSaveFunc = errors.New("incremental load without save func")
+
 func NewDatabaseMemory(opts ...DatabaseOption) *Database {
 	dm := &Database{
 		window: -1,
@@ -26,31 +39,84 @@ func NewDatabaseMemory(opts ...DatabaseOption) *Database {
 }
 
 func (d *Database) Load(ctx context.Context, filter func(index, consumption int, message schema.Message) bool) []schema.Message {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	d.load(ctx)
 	return d.buffer.Load(ctx, filter)
 }
 
 func (d *Database) LoadNext(ctx context.Context, filter func(message schema.Message) bool) *schema.Message {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	d.load(ctx)
-	return d.buffer.LoadNext(ctx, filter)
+	index := d.buffer.index
+	msg := d.buffer.LoadNext(ctx, filter)
+	if d.saveCursorFunc == nil || d.buffer.index == index {
+		return msg
+	}
+	// not delivered when the cursor is not saved
+	if err := d.saveCursorFunc(ctx, d.buffer.index); err != nil {
+		d.buffer.index = index
+		return nil
+	}
+	return msg
 }
 
 func (d *Database) Save(ctx context.Context, msg schema.Message) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	if err := d.save(ctx, &msg); err != nil {
 		return err
 	}
+	if d.incLoadFunc != nil {
+		return nil
+	}
 	return d.buffer.Save(ctx, msg)
 }
 
-func (d *Database) Clear(_ context.Context) error {
-	return nil
+func (d *Database) Clear(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.clearFunc == nil {
+		return nil
+	}
+	if err := d.clearFunc(ctx); err != nil {
+		return err
+	}
+	return d.buffer.Clear(ctx)
+}
+
+// Snapshot returns the messages loaded and the consumption cursor.
+func (d *Database) Snapshot(ctx context.Context) ([]schema.Message, int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.load(ctx)
+	return d.buffer.Snapshot(ctx)
+}
+
+// Restore restores the consumption cursor and the messages, which are not
+// saved to the database again. It is not supported with a load func, the
+// messages being replaced by the ones of the database on the next load.
+func (d *Database) Restore(ctx context.Context, messages []schema.Message, consumption int) error {
+	if d.loadFunc != nil && d.incLoadFunc == nil {
+		return fmt.Errorf("%w: the messages are reloaded from the database", ErrSnapshotUnsupported)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.buffer.Restore(ctx, messages, consumption)
 }
 
 func (d *Database) load(ctx context.Context) {
-	if d.loadFunc != nil {
-		_ = d.buffer.Clear(ctx)
-		messages := d.loadFunc(ctx)
-		d.buffer.Messages = append(d.buffer.Messages, messages...)
+	switch {
+	case d.incLoadFunc != nil:
+		d.buffer.extend(d.incLoadFunc(ctx, len(d.buffer.Messages)))
+	case d.loadFunc != nil:
+		d.buffer.replace(d.loadFunc(ctx))
+	}
+	if d.loadCursorFunc != nil && !d.cursorLoaded {
+		if cursor, err := d.loadCursorFunc(ctx); err == nil {
+			d.buffer.index, d.cursorLoaded = cursor, true
+		}
 	}
 }
 
@@ -58,5 +124,9 @@ func (d *Database) save(ctx context.Context, msg *schema.Message) error {
 	if d.saveFunc != nil {
 		return d.saveFunc(ctx, msg)
 	}
+	// the messages saved are only loaded back from the database
+	if d.incLoadFunc != nil {
+		return ErrMissingSaveFunc
+	}
 	return nil
 }
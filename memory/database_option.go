@@ -31,3 +31,31 @@ func WithLoadFunc(fun func(ctx context.Context) []schema.Message) DatabaseOption
 		b.loadFunc = fun
 	}
 }
+
+// WithIncrementalLoadFunc is an option for providing a load func returning
+// the messages after the first after ones only, instead of all of them.
+// The messages saved are then loaded back from the database, so Save
+// returns ErrMissingSaveFunc without WithSaveFunc.
+func WithIncrementalLoadFunc(fun func(ctx context.Context, after int) []schema.Message) DatabaseOption {
+	return func(b *Database) {
+		b.incLoadFunc = fun
+	}
+}
+
+// WithCursorFuncs is an option for persisting the consumption cursor, the
+// number of messages consumed. load is called on the first load, and save
+// before a message is returned by LoadNext, so a message is not delivered
+// twice after a restart.
+func WithCursorFuncs(load func(ctx context.Context) (int, error),
+	save func(ctx context.Context, cursor int) error) DatabaseOption {
+	return func(b *Database) {
+		b.loadCursorFunc, b.saveCursorFunc = load, save
+	}
+}
+
+// WithClearFunc is an option for providing the clear func.
+func WithClearFunc(fun func(ctx context.Context) error) DatabaseOption {
+	return func(b *Database) {
+		b.clearFunc = fun
+	}
+}
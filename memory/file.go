@@ -0,0 +1,317 @@
+package memory
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/antgroup/aievo/schema"
+)
+
+var ErrCorruptedFile = errors.New("corrupted memory file")
+
+type SyncPolicy int
+
+const (
+	// SyncAlways syncs the file after every write, nothing is lost on a
+	// crash
+	SyncAlways SyncPolicy = iota
+	// SyncCursor syncs the file when the consumption cursor moves, the
+	// messages saved since may be lost on a crash, but no message is ever
+	// delivered twice
+	SyncCursor
+	// SyncNever leaves the syncs to the OS
+	SyncNever
+)
+
+const (
+	_opSave   = "save"
+	_opInsert = "insert"
+	_opCursor = "cursor"
+	// _opReset replaces all the messages and the cursor
+	_opReset = "reset"
+)
+
+// record is a line of the file, an operation on the memory.
+type record struct {
+	Op       string           `json:"op"`
+	Message  *schema.Message  `json:"message,omitempty"`
+	Messages []schema.Message `json:"messages,omitempty"`
+	Index    int              `json:"index,omitempty"`
+}
+
+// File keeps the messages in an append-only JSON lines file, with the
+// consumption cursor, so a run can go on after a restart. It is safe for
+// concurrent use, but the file must not be shared by several memories.
+type File struct {
+	path   string
+	policy SyncPolicy
+	buffer *Buffer
+	file   *os.File
+	// records is the number of records in the file
+	records int
+
+	mu sync.Mutex
+}
+
+var (
+	_ schema.Memory         = (*File)(nil)
+	_ schema.PriorityMemory = (*File)(nil)
+	_ schema.SnapshotMemory = (*File)(nil)
+)
+
+type FileOption func(f *File)
+
+// WithSyncPolicy sets when the file is synced, SyncAlways by default.
+func WithSyncPolicy(policy SyncPolicy) FileOption {
+	return func(f *File) {
+		f.policy = policy
+	}
+}
+
+// NewFileMemory opens the memory kept in the file at path, created if it
+// does not exist. A last line partially written by a crash is dropped.
+func NewFileMemory(path string, opts ...FileOption) (*File, error) {
+	f := &File{path: path, buffer: NewBufferMemory()}
+	for _, opt := range opts {
+		opt(f)
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err = f.replay(file); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	f.file = file
+	// the cursor moves are not needed once replayed
+	if f.records > len(f.buffer.Messages)+1 {
+		if err = f.compact(); err != nil {
+			_ = f.file.Close()
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// replay applies the records of file, and positions it at the end of the
+// last complete record.
+func (f *File) replay(file *os.File) error {
+	reader := bufio.NewReader(file)
+	offset := int64(0)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// a record is complete with its newline only
+			break
+		}
+		if err != nil {
+			return err
+		}
+		rec := &record{}
+		if err = json.Unmarshal(line, rec); err != nil {
+			return fmt.Errorf("%w: %s: offset %d: %w", ErrCorruptedFile, f.path, offset, err)
+		}
+		if err = f.apply(rec); err != nil {
+			return fmt.Errorf("%w: %s: offset %d: %w", ErrCorruptedFile, f.path, offset, err)
+		}
+		offset += int64(len(line))
+		f.records++
+	}
+	if err := file.Truncate(offset); err != nil {
+		return err
+	}
+	_, err := file.Seek(offset, io.SeekStart)
+	return err
+}
+
+func (f *File) apply(rec *record) error {
+	b := f.buffer
+	switch rec.Op {
+	case _opSave:
+		if rec.Message == nil {
+			return errors.New("save without message")
+		}
+		b.Messages = append(b.Messages, *rec.Message)
+	case _opInsert:
+		if rec.Index < 0 || rec.Index > len(b.Messages) {
+			return fmt.Errorf("insert at %d of %d messages", rec.Index, len(b.Messages))
+		}
+		b.Messages = append(b.Messages[:rec.Index],
+			append(append([]schema.Message{}, rec.Messages...), b.Messages[rec.Index:]...)...)
+	case _opCursor:
+		if rec.Index < 0 || rec.Index > len(b.Messages) {
+			return fmt.Errorf("cursor at %d of %d messages", rec.Index, len(b.Messages))
+		}
+		b.index = rec.Index
+	case _opReset:
+		if rec.Index < 0 || rec.Index > len(rec.Messages) {
+			return fmt.Errorf("cursor at %d of %d messages", rec.Index, len(rec.Messages))
+		}
+		b.Messages, b.index = append([]schema.Message{}, rec.Messages...), rec.Index
+	default:
+		return fmt.Errorf("unknown operation %q", rec.Op)
+	}
+	return nil
+}
+
+// write appends rec to the file, the memory is changed by the caller once
+// it is written. A record failing to be written is removed, so no partial
+// line is left before the next ones.
+func (f *File) write(rec *record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	offset, err := f.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	_, err = f.file.Write(append(data, '\n'))
+	if err == nil && (f.policy == SyncAlways || f.policy == SyncCursor && rec.Op != _opSave) {
+		err = f.file.Sync()
+	}
+	if err != nil {
+		return f.rollback(offset, err)
+	}
+	f.records++
+	return nil
+}
+
+// rollback truncates the file to offset after err.
+func (f *File) rollback(offset int64, err error) error {
+	if terr := f.file.Truncate(offset); terr != nil {
+		return errors.Join(err, terr)
+	}
+	if _, serr := f.file.Seek(offset, io.SeekStart); serr != nil {
+		return errors.Join(err, serr)
+	}
+	return err
+}
+
+// compact rewrites the file with a single record of the messages and the
+// cursor.
+func (f *File) compact() error {
+	var buf bytes.Buffer
+	if len(f.buffer.Messages) != 0 || f.buffer.index != 0 {
+		data, err := json.Marshal(&record{Op: _opReset, Messages: f.buffer.Messages, Index: f.buffer.index})
+		if err != nil {
+			return err
+		}
+		buf.Write(append(data, '\n'))
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(buf.Bytes()); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = os.Rename(tmp.Name(), f.path); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	_ = f.file.Close()
+	f.file = tmp
+	f.records = 0
+	if buf.Len() != 0 {
+		f.records = 1
+	}
+	return nil
+}
+
+// Compact rewrites the file without the history of the cursor.
+func (f *File) Compact() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.compact()
+}
+
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Close()
+}
+
+func (f *File) Load(ctx context.Context, filter func(index, consumption int, message schema.Message) bool) []schema.Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.buffer.Load(ctx, filter)
+}
+
+// LoadNext returns the next message, once the moved cursor is written. It
+// returns nil when the cursor cannot be written.
+func (f *File) LoadNext(ctx context.Context, filter func(message schema.Message) bool) *schema.Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	index := f.buffer.index
+	msg := f.buffer.LoadNext(ctx, filter)
+	if f.buffer.index == index {
+		return msg
+	}
+	if err := f.write(&record{Op: _opCursor, Index: f.buffer.index}); err != nil {
+		f.buffer.index = index
+		return nil
+	}
+	return msg
+}
+
+func (f *File) Save(ctx context.Context, msg schema.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.write(&record{Op: _opSave, Message: &msg}); err != nil {
+		return err
+	}
+	return f.buffer.Save(ctx, msg)
+}
+
+func (f *File) SaveNext(ctx context.Context, msgs ...schema.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.write(&record{Op: _opInsert, Messages: msgs, Index: f.buffer.index}); err != nil {
+		return err
+	}
+	return f.buffer.SaveNext(ctx, msgs...)
+}
+
+func (f *File) Clear(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.buffer.Clear(ctx); err != nil {
+		return err
+	}
+	return f.compact()
+}
+
+func (f *File) Snapshot(ctx context.Context) ([]schema.Message, int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.buffer.Snapshot(ctx)
+}
+
+func (f *File) Restore(ctx context.Context, messages []schema.Message, consumption int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if consumption < 0 || consumption > len(messages) {
+		return fmt.Errorf("%w: consumption %d of %d messages",
+			ErrInvalidSnapshot, consumption, len(messages))
+	}
+	if err := f.write(&record{Op: _opReset, Messages: messages, Index: consumption}); err != nil {
+		return err
+	}
+	return f.buffer.Restore(ctx, messages, consumption)
+}
@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/antgroup/aievo/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func msg(content string) schema.Message {
+	return schema.Message{Type: schema.MsgTypeMsg, Sender: "a", Receiver: "b", Content: content}
+}
+
+func contents(messages []schema.Message) []string {
+	result := make([]string, 0, len(messages))
+	for _, m := range messages {
+		result = append(result, m.Content)
+	}
+	return result
+}
+
+func TestFileMemory(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "memory.jsonl")
+	m, err := NewFileMemory(path)
+	require.NoError(t, err)
+	for _, c := range []string{"1", "2", "3"} {
+		require.NoError(t, m.Save(ctx, msg(c)))
+	}
+	assert.Equal(t, "1", m.LoadNext(ctx, nil).Content)
+	require.NoError(t, m.SaveNext(ctx, msg("urgent")))
+	assert.Equal(t, "urgent", m.LoadNext(ctx, nil).Content)
+	require.NoError(t, m.Close())
+
+	// a crash while writing a record
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"op":"save","mess`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	m, err = NewFileMemory(path, WithSyncPolicy(SyncCursor))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "urgent", "2", "3"}, contents(m.Load(ctx, nil)))
+	assert.Equal(t, "2", m.LoadNext(ctx, nil).Content)
+	require.NoError(t, m.Close())
+
+	// the cursor moves were compacted
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(data), "\n"))
+
+	m, err = NewFileMemory(path)
+	require.NoError(t, err)
+	assert.Equal(t, "3", m.LoadNext(ctx, nil).Content)
+	assert.Nil(t, m.LoadNext(ctx, nil))
+	require.NoError(t, m.Clear(ctx))
+	require.NoError(t, m.Close())
+	m, err = NewFileMemory(path)
+	require.NoError(t, err)
+	assert.Empty(t, m.Load(ctx, nil))
+	require.NoError(t, m.Close())
+
+	require.NoError(t, os.WriteFile(path, []byte("{\"op\":\"cursor\",\"index\":3}\n"), 0o644))
+	_, err = NewFileMemory(path)
+	assert.ErrorIs(t, err, ErrCorruptedFile)
+}
+
+func TestDatabaseRestore(t *testing.T) {
+	ctx := context.Background()
+	d := NewDatabaseMemory(WithLoadFunc(func(context.Context) []schema.Message {
+		return []schema.Message{msg("1")}
+	}))
+	assert.ErrorIs(t, d.Restore(ctx, []schema.Message{msg("2")}, 0), ErrSnapshotUnsupported)
+	assert.Equal(t, []string{"1"}, contents(d.Load(ctx, nil)))
+
+	d = NewDatabaseMemory()
+	require.NoError(t, d.Restore(ctx, []schema.Message{msg("2")}, 1))
+	assert.Equal(t, []string{"2"}, contents(d.Load(ctx, nil)))
+	assert.Nil(t, d.LoadNext(ctx, nil))
+}
+
+func TestIncrementalDatabase(t *testing.T) {
+	ctx := context.Background()
+	var (
+		db     []schema.Message
+		cursor int
+		loaded []int
+	)
+	open := func() *Database {
+		return NewDatabaseMemory(
+			WithSaveFunc(func(_ context.Context, m *schema.Message) error {
+				db = append(db, *m)
+				return nil
+			}),
+			WithIncrementalLoadFunc(func(_ context.Context, after int) []schema.Message {
+				loaded = append(loaded, after)
+				return db[after:]
+			}),
+			WithCursorFuncs(func(context.Context) (int, error) {
+				return cursor, nil
+			}, func(_ context.Context, c int) error {
+				cursor = c
+				return nil
+			}))
+	}
+
+	d := open()
+	require.NoError(t, d.Save(ctx, msg("1")))
+	require.NoError(t, d.Save(ctx, msg("2")))
+	assert.Equal(t, "1", d.LoadNext(ctx, nil).Content)
+	assert.Equal(t, []string{"1", "2"}, contents(d.Load(ctx, nil)))
+	assert.Equal(t, []int{0, 2}, loaded)
+	assert.Equal(t, 1, cursor)
+
+	// after a restart, the first message is not delivered again
+	d = open()
+	assert.Equal(t, "2", d.LoadNext(ctx, nil).Content)
+	assert.Nil(t, d.LoadNext(ctx, nil))
+
+	// the messages saved would be lost without a save func
+	d = NewDatabaseMemory(WithIncrementalLoadFunc(func(context.Context, int) []schema.Message {
+		return nil
+	}))
+	assert.ErrorIs(t, d.Save(ctx, msg("1")), ErrMissingSaveFunc)
+}
@@ -0,0 +1,213 @@
+package longterm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/antgroup/aievo/llm"
+	"github.com/antgroup/aievo/prompt"
+	"github.com/antgroup/aievo/schema"
+)
+
+const (
+	_defaultTopK = 5
+	_none        = "NONE"
+)
+
+const _defaultExtractPrompt = `You are the long-term memory of {{.name}}, a member of a team. Below is the conversation {{.name}} took part in during a task.
+Extract the facts worth remembering for the future tasks of {{.name}}: the facts learned about the people and systems involved, the preferences stated, the decisions made and the outcome of the task.
+Each fact MUST be self-contained, as it will be read without the conversation. Do not make up anything.
+Answer with one fact per line, without numbering, or {{.none}} when nothing is worth remembering.
+
+Conversation:
+~~~
+{{.conversation}}
+~~~
+
+Facts:
+`
+
+var (
+	ErrMissingEmbedder = errors.New("missing embedder")
+	ErrMissingStore    = errors.New("missing store")
+)
+
+// Embedder turns texts into vectors, e.g. with an embedding model.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// EmbedderFunc is a function used as an Embedder.
+type EmbedderFunc func(ctx context.Context, texts []string) ([][]float32, error)
+
+func (f EmbedderFunc) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	return f(ctx, texts)
+}
+
+// Owner is implemented by the agents with a long-term memory, which the
+// team fills at the end of each run.
+type Owner interface {
+	LongTermMemory() *Memory
+}
+
+// Memory remembers the salient facts of the runs of agents, and recalls the
+// ones relevant to a new task.
+type Memory struct {
+	llm      llm.LLM
+	embedder Embedder
+	store    Store
+	tpl      *prompt.PromptTemplate
+	topK     int
+	minScore float32
+}
+
+type Option func(m *Memory)
+
+// WithTopK sets the max number of facts recalled, 5 by default.
+func WithTopK(k int) Option {
+	return func(m *Memory) {
+		m.topK = k
+	}
+}
+
+// WithMinScore sets the similarity below which a fact is not recalled.
+func WithMinScore(score float32) Option {
+	return func(m *Memory) {
+		m.minScore = score
+	}
+}
+
+// WithExtractPrompt sets the prompt template extracting the facts, which
+// is given the agent in {{.name}} and its messages in {{.conversation}}.
+// The facts are read one per line.
+func WithExtractPrompt(template string) Option {
+	return func(m *Memory) {
+		m.tpl, _ = prompt.NewPromptTemplate(template)
+	}
+}
+
+func NewMemory(LLM llm.LLM, embedder Embedder, store Store, opts ...Option) (*Memory, error) {
+	if LLM == nil {
+		return nil, schema.ErrMissingLLM
+	}
+	if embedder == nil {
+		return nil, ErrMissingEmbedder
+	}
+	if store == nil {
+		return nil, ErrMissingStore
+	}
+	tpl, err := prompt.NewPromptTemplate(_defaultExtractPrompt)
+	if err != nil {
+		return nil, err
+	}
+	m := &Memory{
+		llm:      LLM,
+		embedder: embedder,
+		store:    store,
+		tpl:      tpl,
+		topK:     _defaultTopK,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.tpl == nil {
+		return nil, schema.ErrParsePromptTemplate
+	}
+	return m, nil
+}
+
+// Remember extracts the facts worth remembering from the messages of agent
+// and stores them.
+func (m *Memory) Remember(ctx context.Context, agent string, messages []schema.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	p, err := m.tpl.Format(map[string]any{
+		"name":         agent,
+		"none":         _none,
+		"conversation": convertConversation(messages),
+	})
+	if err != nil {
+		return err
+	}
+	output, err := m.llm.Generate(ctx, p)
+	if err != nil {
+		return err
+	}
+	facts := parseFacts(output.Content)
+	if len(facts) == 0 {
+		return nil
+	}
+	vectors, err := m.embedder.Embed(ctx, facts)
+	if err != nil {
+		return err
+	}
+	if len(vectors) != len(facts) {
+		return fmt.Errorf("%d vectors embedded for %d facts", len(vectors), len(facts))
+	}
+	now := time.Now()
+	records := make([]Record, 0, len(facts))
+	for i, fact := range facts {
+		records = append(records, Record{Agent: agent, Content: fact, Vector: vectors[i], Time: now})
+	}
+	return m.store.Add(ctx, records...)
+}
+
+// Recall returns the facts of agent most relevant to query, the most
+// relevant first.
+func (m *Memory) Recall(ctx context.Context, agent, query string) ([]schema.Document, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, nil
+	}
+	vectors, err := m.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("%d vectors embedded for a query", len(vectors))
+	}
+	docs, err := m.store.Search(ctx, agent, vectors[0], m.topK)
+	if err != nil {
+		return nil, err
+	}
+	result := docs[:0]
+	for _, doc := range docs {
+		if doc.Score >= m.minScore {
+			result = append(result, doc)
+		}
+	}
+	return result, nil
+}
+
+// Format renders the facts recalled for the {{.memories}} prompt variable.
+func Format(docs []schema.Document) string {
+	lines := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		lines = append(lines, "- "+doc.PageContent)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func parseFacts(content string) []string {
+	facts := make([]string, 0)
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "-*•"))
+		if line == "" || strings.EqualFold(line, _none) {
+			continue
+		}
+		facts = append(facts, line)
+	}
+	return facts
+}
+
+func convertConversation(messages []schema.Message) string {
+	var conversation strings.Builder
+	for _, message := range messages {
+		_, _ = fmt.Fprintf(&conversation, "(%s -> %s): %s\n",
+			message.Sender, message.Receiver, message.Content)
+	}
+	return conversation.String()
+}
@@ -0,0 +1,88 @@
+package longterm
+
+import (
+	"context"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/antgroup/aievo/llm"
+	"github.com/antgroup/aievo/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLLM answers with the facts set for the agent named in the prompt.
+type fakeLLM struct {
+	facts map[string]string
+}
+
+func (f *fakeLLM) Generate(_ context.Context, prompt string, _ ...llm.GenerateOption) (*llm.Generation, error) {
+	for name, facts := range f.facts {
+		if strings.HasPrefix(prompt, "You are the long-term memory of "+name+",") {
+			return &llm.Generation{Content: facts}, nil
+		}
+	}
+	return &llm.Generation{Content: "NONE"}, nil
+}
+
+func (f *fakeLLM) GenerateContent(ctx context.Context, _ []llm.Message, _ ...llm.GenerateOption) (*llm.Generation, error) {
+	return f.Generate(ctx, "")
+}
+
+// bagOfWords embeds a text as the counts of its hashed words.
+var bagOfWords = EmbedderFunc(func(_ context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for _, text := range texts {
+		vector := make([]float32, 256)
+		for _, word := range strings.Fields(strings.ToLower(text)) {
+			h := fnv.New32a()
+			_, _ = h.Write([]byte(strings.Trim(word, ".,?")))
+			vector[h.Sum32()%256]++
+		}
+		vectors = append(vectors, vector)
+	}
+	return vectors, nil
+})
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "longterm.jsonl")
+	store, err := NewFileStore(path)
+	require.NoError(t, err)
+	LLM := &fakeLLM{facts: map[string]string{
+		"triage": "- Acme reports outages of the billing service\n\n* Acme prefers answers in French\n",
+	}}
+	m, err := NewMemory(LLM, bagOfWords, store, WithTopK(1))
+	require.NoError(t, err)
+
+	conversation := []schema.Message{{Sender: "User", Receiver: "triage", Content: "billing is down again"}}
+	require.NoError(t, m.Remember(ctx, "triage", conversation))
+	require.NoError(t, m.Remember(ctx, "writer", conversation))
+	// the facts already kept are not added again
+	require.NoError(t, m.Remember(ctx, "triage", conversation))
+	require.NoError(t, store.Close())
+
+	store, err = NewFileStore(path)
+	require.NoError(t, err)
+	defer store.Close()
+	m, err = NewMemory(LLM, bagOfWords, store, WithTopK(1))
+	require.NoError(t, err)
+	docs, err := m.Recall(ctx, "triage", "Acme prefers answers in which language?")
+	require.NoError(t, err)
+	assert.Equal(t, "- Acme prefers answers in French", Format(docs))
+	docs, err = m.Recall(ctx, "writer", "Acme prefers answers in which language?")
+	require.NoError(t, err)
+	assert.Empty(t, docs)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(data), "\n"))
+
+	_, err = NewMemory(LLM, nil, store)
+	assert.ErrorIs(t, err, ErrMissingEmbedder)
+	_, err = NewMemory(LLM, bagOfWords, store, WithExtractPrompt("{{"))
+	assert.ErrorIs(t, err, schema.ErrParsePromptTemplate)
+}
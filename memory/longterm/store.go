@@ -0,0 +1,174 @@
+package longterm
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/antgroup/aievo/schema"
+)
+
+var ErrCorruptedStore = errors.New("corrupted long-term memory store")
+
+// Record is a fact remembered for an agent.
+type Record struct {
+	Agent   string    `json:"agent"`
+	Content string    `json:"content"`
+	Vector  []float32 `json:"vector"`
+	Time    time.Time `json:"time"`
+}
+
+// Store keeps the records of the agents and finds the ones nearest to a
+// vector.
+type Store interface {
+	Add(ctx context.Context, records ...Record) error
+	// Search returns the k records of agent nearest to vector, as
+	// documents scored by their similarity, the nearest first
+	Search(ctx context.Context, agent string, vector []float32, k int) ([]schema.Document, error)
+}
+
+// FileStore keeps the records in memory, indexed by agent, and appends them
+// to a JSON lines file, so they are kept across runs. The search compares
+// vector to every record of the agent, which is fast enough for the
+// thousands of facts of a local use. It is safe for concurrent use.
+type FileStore struct {
+	path    string
+	file    *os.File
+	records map[string][]Record
+
+	mu sync.RWMutex
+}
+
+var _ Store = (*FileStore)(nil)
+
+// NewFileStore opens the store kept in the file at path, created if it does
+// not exist. A last line partially written by a crash is dropped.
+func NewFileStore(path string) (*FileStore, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s := &FileStore{path: path, file: file, records: make(map[string][]Record)}
+	if err = s.load(); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileStore) load() error {
+	reader := bufio.NewReader(s.file)
+	offset := int64(0)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		record := Record{}
+		if err = json.Unmarshal(line, &record); err != nil {
+			return fmt.Errorf("%w: %s: offset %d: %w", ErrCorruptedStore, s.path, offset, err)
+		}
+		s.records[record.Agent] = append(s.records[record.Agent], record)
+		offset += int64(len(line))
+	}
+	if err := s.file.Truncate(offset); err != nil {
+		return err
+	}
+	_, err := s.file.Seek(offset, io.SeekStart)
+	return err
+}
+
+// Add appends records to the store, except the ones already kept for the
+// same agent.
+func (s *FileStore) Add(_ context.Context, records ...Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var buf []byte
+	added := make([]Record, 0, len(records))
+	for _, record := range records {
+		if s.contains(record, added) {
+			continue
+		}
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		buf = append(append(buf, data...), '\n')
+		added = append(added, record)
+	}
+	if len(added) == 0 {
+		return nil
+	}
+	if _, err := s.file.Write(buf); err != nil {
+		return err
+	}
+	if err := s.file.Sync(); err != nil {
+		return err
+	}
+	for _, record := range added {
+		s.records[record.Agent] = append(s.records[record.Agent], record)
+	}
+	return nil
+}
+
+func (s *FileStore) contains(record Record, added []Record) bool {
+	for _, r := range append(s.records[record.Agent], added...) {
+		if r.Agent == record.Agent && r.Content == record.Content {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *FileStore) Search(_ context.Context, agent string, vector []float32, k int) ([]schema.Document, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	docs := make([]schema.Document, 0, len(s.records[agent]))
+	for _, record := range s.records[agent] {
+		docs = append(docs, schema.Document{
+			PageContent: record.Content,
+			Metadata:    map[string]any{"agent": record.Agent, "time": record.Time},
+			Score:       cosine(vector, record.Vector),
+		})
+	}
+	sort.SliceStable(docs, func(i, j int) bool {
+		return docs[i].Score > docs[j].Score
+	})
+	if k > 0 && len(docs) > k {
+		docs = docs[:k]
+	}
+	return docs, nil
+}
+
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+func cosine(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return float32(dot / math.Sqrt(na*nb))
+}
@@ -0,0 +1,294 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+
+	"github.com/antgroup/aievo/callback"
+	"github.com/antgroup/aievo/llm"
+	"github.com/antgroup/aievo/prompt"
+	"github.com/antgroup/aievo/schema"
+	"github.com/antgroup/aievo/utils"
+)
+
+const (
+	SummarySender = "Summary"
+
+	_defaultSummaryBudget = 4096
+	_defaultSummaryKeep   = 4
+	_defaultSummaryCache  = 128
+)
+
+const _defaultSummaryPrompt = `Progressively summarize the lines of conversation below, adding onto the previous summary and returning a new summary.
+The summary MUST keep the original task, the decisions made, the key facts stated by each participant and what is still pending. Be concise and do not make up anything.
+
+Previous summary:
+~~~
+{{.summary}}
+~~~
+
+New lines of conversation:
+~~~
+{{.conversation}}
+~~~
+
+New summary:
+`
+
+// Summarizer condenses the older messages into a running summary message
+// when the messages exceed a token budget. Summaries are cached by the
+// messages they cover, so a growing conversation is summarized
+// incrementally and each agent's view of it keeps its own summary.
+type Summarizer struct {
+	llm    llm.LLM
+	tpl    *prompt.PromptTemplate
+	budget int
+	keep   int
+	limit  int
+	errors callback.ErrorHandler
+
+	mu    sync.Mutex
+	cache map[uint64]string
+	order []uint64
+}
+
+type SummaryOption func(s *Summarizer)
+
+// WithTokenBudget sets the estimated tokens the messages may take before
+// the older ones are summarized.
+func WithTokenBudget(budget int) SummaryOption {
+	return func(s *Summarizer) {
+		s.budget = budget
+	}
+}
+
+// WithKeepRecent sets the number of latest messages never summarized.
+func WithKeepRecent(keep int) SummaryOption {
+	return func(s *Summarizer) {
+		s.keep = keep
+	}
+}
+
+// WithSummaryPrompt sets the prompt template, which is given the previous
+// summary in {{.summary}} and the new messages in {{.conversation}}.
+func WithSummaryPrompt(template string) SummaryOption {
+	return func(s *Summarizer) {
+		s.tpl, _ = prompt.NewPromptTemplate(template)
+	}
+}
+
+// WithSummaryCache sets the max number of summaries cached.
+func WithSummaryCache(limit int) SummaryOption {
+	return func(s *Summarizer) {
+		s.limit = limit
+	}
+}
+
+// WithSummaryErrorHandler sets the handler told when a summary fails, the
+// messages being then returned whole.
+func WithSummaryErrorHandler(handler callback.ErrorHandler) SummaryOption {
+	return func(s *Summarizer) {
+		s.errors = handler
+	}
+}
+
+func NewSummarizer(LLM llm.LLM, opts ...SummaryOption) (*Summarizer, error) {
+	if LLM == nil {
+		return nil, schema.ErrMissingLLM
+	}
+	tpl, err := prompt.NewPromptTemplate(_defaultSummaryPrompt)
+	if err != nil {
+		return nil, err
+	}
+	s := &Summarizer{
+		llm:    LLM,
+		tpl:    tpl,
+		budget: _defaultSummaryBudget,
+		keep:   _defaultSummaryKeep,
+		limit:  _defaultSummaryCache,
+		cache:  make(map[uint64]string),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.tpl == nil {
+		return nil, schema.ErrParsePromptTemplate
+	}
+	return s, nil
+}
+
+// Summarize returns messages unchanged when they fit in the budget, or else
+// a summary message of the older ones followed by the latest ones. When
+// the llm fails, messages are returned unchanged and the error is told to
+// the error handler.
+func (s *Summarizer) Summarize(ctx context.Context, messages []schema.Message) []schema.Message {
+	total := 0
+	for _, message := range messages {
+		total += estimate(message)
+	}
+	if total <= s.budget {
+		return messages
+	}
+
+	// keep the latest messages within half of the budget, the summary
+	// takes the rest
+	cut, recent := len(messages), 0
+	for cut > 0 {
+		tokens := estimate(messages[cut-1])
+		if len(messages)-cut >= s.keep && recent+tokens > s.budget/2 {
+			break
+		}
+		recent += tokens
+		cut--
+	}
+	if cut == 0 {
+		return messages
+	}
+
+	summary, err := s.summarize(ctx, messages[:cut])
+	if err != nil {
+		if s.errors != nil {
+			s.errors.HandleError(ctx, fmt.Errorf("summarize %d messages: %w", cut, err))
+		}
+		return messages
+	}
+	result := make([]schema.Message, 0, len(messages)-cut+1)
+	result = append(result, schema.Message{
+		Type:     schema.MsgTypeMsg,
+		Content:  summary,
+		Sender:   SummarySender,
+		Receiver: schema.MsgAllReceiver,
+	})
+	return append(result, messages[cut:]...)
+}
+
+// FilterFunc returns Summarize as a filter for
+// agent.WithContextFilterMemoryFunc, so the summary is cancelled with the
+// run.
+func (s *Summarizer) FilterFunc() func(context.Context, []schema.Message) []schema.Message {
+	return s.Summarize
+}
+
+func (s *Summarizer) summarize(ctx context.Context, messages []schema.Message) (string, error) {
+	// hashes[i] is the hash of messages[:i+1]
+	hashes := make([]uint64, len(messages))
+	h := fnv.New64a()
+	for i, message := range messages {
+		_, _ = fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s\x00", message.Type,
+			message.Sender, message.Receiver, message.Content)
+		hashes[i] = h.Sum64()
+	}
+
+	// start from the summary of the longest prefix already summarized
+	previous, start := "", 0
+	s.mu.Lock()
+	for i := len(messages) - 1; i >= 0; i-- {
+		if summary, ok := s.cache[hashes[i]]; ok {
+			previous, start = summary, i+1
+			break
+		}
+	}
+	s.mu.Unlock()
+	if start == len(messages) {
+		return previous, nil
+	}
+
+	p, err := s.tpl.Format(map[string]any{
+		"summary":      previous,
+		"conversation": convertConversation(messages[start:]),
+	})
+	if err != nil {
+		return "", err
+	}
+	output, err := s.llm.Generate(ctx, p)
+	if err != nil {
+		return "", err
+	}
+	summary := strings.TrimSpace(output.Content)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := hashes[len(hashes)-1]
+	if _, ok := s.cache[key]; !ok {
+		s.order = append(s.order, key)
+	}
+	s.cache[key] = summary
+	for s.limit > 0 && len(s.order) > s.limit {
+		delete(s.cache, s.order[0])
+		s.order = s.order[1:]
+	}
+	return summary, nil
+}
+
+func convertConversation(messages []schema.Message) string {
+	var conversation strings.Builder
+	for _, message := range messages {
+		if message.Sender == SummarySender {
+			continue
+		}
+		if message.Condition != "" {
+			_, _ = fmt.Fprintf(&conversation, "(%s -> %s)(%s): %s\n", message.Sender,
+				message.Receiver, message.Condition, message.Content)
+			continue
+		}
+		_, _ = fmt.Fprintf(&conversation, "(%s -> %s): %s\n",
+			message.Sender, message.Receiver, message.Content)
+	}
+	return conversation.String()
+}
+
+func estimate(message schema.Message) int {
+	// a few tokens for the sender and receiver
+	return utils.EstimateTokens(message.Content) + 8
+}
+
+// SummaryMemory wraps a memory, summarizing the messages it loads.
+type SummaryMemory struct {
+	schema.Memory
+	summarizer *Summarizer
+}
+
+var _ schema.Memory = (*SummaryMemory)(nil)
+
+// SaveNext saves msgs ahead of the messages not consumed yet when the
+// wrapped memory supports it, or else after them.
+func (s *SummaryMemory) SaveNext(ctx context.Context, msgs ...schema.Message) error {
+	if pm, ok := s.Memory.(schema.PriorityMemory); ok {
+		return pm.SaveNext(ctx, msgs...)
+	}
+	for _, msg := range msgs {
+		if err := s.Memory.Save(ctx, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Snapshot snapshots the wrapped memory, which must be a
+// schema.SnapshotMemory.
+func (s *SummaryMemory) Snapshot(ctx context.Context) ([]schema.Message, int, error) {
+	sm, ok := s.Memory.(schema.SnapshotMemory)
+	if !ok {
+		return nil, 0, ErrSnapshotUnsupported
+	}
+	return sm.Snapshot(ctx)
+}
+
+func (s *SummaryMemory) Restore(ctx context.Context, messages []schema.Message, consumption int) error {
+	sm, ok := s.Memory.(schema.SnapshotMemory)
+	if !ok {
+		return ErrSnapshotUnsupported
+	}
+	return sm.Restore(ctx, messages, consumption)
+}
+
+func NewSummaryMemory(memory schema.Memory, summarizer *Summarizer) *SummaryMemory {
+	return &SummaryMemory{Memory: memory, summarizer: summarizer}
+}
+
+func (s *SummaryMemory) Load(ctx context.Context, filter func(index, consumption int, message schema.Message) bool) []schema.Message {
+	return s.summarizer.Summarize(ctx, s.Memory.Load(ctx, filter))
+}
@@ -0,0 +1,110 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/antgroup/aievo/llm"
+	"github.com/antgroup/aievo/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLLM answers with the number of prompts it has been given.
+type fakeLLM struct {
+	prompts []string
+}
+
+func (f *fakeLLM) Generate(ctx context.Context, prompt string, _ ...llm.GenerateOption) (*llm.Generation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.prompts = append(f.prompts, prompt)
+	return &llm.Generation{Content: fmt.Sprintf("summary %d", len(f.prompts))}, nil
+}
+
+func (f *fakeLLM) GenerateContent(ctx context.Context, _ []llm.Message, _ ...llm.GenerateOption) (*llm.Generation, error) {
+	return f.Generate(ctx, "")
+}
+
+func messages(n int) []schema.Message {
+	msgs := make([]schema.Message, 0, n)
+	for i := 0; i < n; i++ {
+		msgs = append(msgs, schema.Message{
+			Type:     schema.MsgTypeMsg,
+			Sender:   "God",
+			Receiver: "ALL",
+			Content:  fmt.Sprintf("message %d %s", i, strings.Repeat("x", 92)),
+		})
+	}
+	return msgs
+}
+
+func TestSummarizer(t *testing.T) {
+	fake := &fakeLLM{}
+	// each message is about 34 tokens
+	summarizer, err := NewSummarizer(fake, WithTokenBudget(250), WithKeepRecent(2))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	assert.Len(t, summarizer.Summarize(ctx, messages(6)), 6)
+	assert.Empty(t, fake.prompts)
+
+	summarized := summarizer.Summarize(ctx, messages(10))
+	require.Len(t, summarized, 4)
+	assert.Equal(t, SummarySender, summarized[0].Sender)
+	assert.Equal(t, "summary 1", summarized[0].Content)
+	assert.Equal(t, messages(10)[7:], summarized[1:])
+	assert.Contains(t, fake.prompts[0], "message 0")
+	assert.Contains(t, fake.prompts[0], "message 6")
+
+	// the same view is served from the cache
+	assert.Equal(t, summarized, summarizer.Summarize(ctx, messages(10)))
+	assert.Len(t, fake.prompts, 1)
+
+	// new messages only summarize what is new
+	summarized = summarizer.Summarize(ctx, messages(12))
+	assert.Equal(t, "summary 2", summarized[0].Content)
+	assert.Contains(t, fake.prompts[1], "summary 1")
+	assert.NotContains(t, fake.prompts[1], "message 6")
+	assert.Contains(t, fake.prompts[1], "message 8")
+}
+
+type errorRecorder struct {
+	errs []error
+}
+
+func (r *errorRecorder) HandleError(_ context.Context, err error) {
+	r.errs = append(r.errs, err)
+}
+
+func TestSummarizerError(t *testing.T) {
+	fake, recorder := &fakeLLM{}, &errorRecorder{}
+	summarizer, err := NewSummarizer(fake, WithTokenBudget(250),
+		WithKeepRecent(2), WithSummaryErrorHandler(recorder))
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// a cancelled run does not wait on the llm, the messages are kept
+	assert.Equal(t, messages(10), summarizer.FilterFunc()(ctx, messages(10)))
+	assert.Empty(t, fake.prompts)
+	require.Len(t, recorder.errs, 1)
+	assert.ErrorIs(t, recorder.errs[0], context.Canceled)
+}
+
+func TestSummaryMemory(t *testing.T) {
+	summarizer, err := NewSummarizer(&fakeLLM{}, WithTokenBudget(100))
+	require.NoError(t, err)
+	memory := NewSummaryMemory(NewBufferMemory(), summarizer)
+	ctx := context.Background()
+	for _, msg := range messages(8) {
+		require.NoError(t, memory.Save(ctx, msg))
+	}
+	loaded := memory.Load(ctx, nil)
+	require.Len(t, loaded, 5)
+	assert.Equal(t, SummarySender, loaded[0].Sender)
+	assert.NotNil(t, memory.LoadNext(ctx, nil))
+}
@@ -0,0 +1,204 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/antgroup/aievo/callback"
+	"github.com/antgroup/aievo/schema"
+	"github.com/antgroup/aievo/utils"
+)
+
+const _defaultTaskSender = "User"
+
+// TokenWindow wraps a memory, loading the most recent messages that fit a
+// token budget instead of a number of messages. The task, i.e. the first
+// message of the user, and the latest SOP message are always kept, and the
+// SOP of the environment of the context, rendered in the prompts of the
+// agents, is taken from the budget. Only the loads of a receiver, with a
+// filter, are windowed; a load without filter returns all the messages,
+// e.g. for a transcript.
+type TokenWindow struct {
+	schema.Memory
+	budget  int
+	sender  string
+	handler callback.MemoryHandler
+
+	// reported are the indexes of the messages told to the handler, each
+	// message being told once whatever the number of loads leaving it out
+	reported map[int]bool
+	mu       sync.Mutex
+}
+
+var (
+	_ schema.PriorityMemory = (*TokenWindow)(nil)
+	_ schema.SnapshotMemory = (*TokenWindow)(nil)
+)
+
+type TokenWindowOption func(w *TokenWindow)
+
+// WithTaskSender sets the sender of the task, "User" by default.
+func WithTaskSender(sender string) TokenWindowOption {
+	return func(w *TokenWindow) {
+		w.sender = sender
+	}
+}
+
+// WithElidedHandler sets the handler told of the messages left out of
+// each load, e.g. a callback.Recorder.
+func WithElidedHandler(handler callback.MemoryHandler) TokenWindowOption {
+	return func(w *TokenWindow) {
+		w.handler = handler
+	}
+}
+
+// NewTokenWindowMemory wraps memory, loading at most budget estimated
+// tokens of messages for each receiver. A budget of 0 loads them all.
+func NewTokenWindowMemory(memory schema.Memory, budget int, opts ...TokenWindowOption) *TokenWindow {
+	w := &TokenWindow{Memory: memory, budget: budget, sender: _defaultTaskSender}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+func (w *TokenWindow) Load(ctx context.Context, filter func(index, consumption int, message schema.Message) bool) []schema.Message {
+	if filter == nil {
+		return w.Memory.Load(ctx, nil)
+	}
+	var indexes []int
+	messages := w.Memory.Load(ctx, func(index, consumption int, message schema.Message) bool {
+		ok := filter(index, consumption, message)
+		if ok {
+			indexes = append(indexes, index)
+		}
+		return ok
+	})
+	// the wrapped memory may keep the last ones only, e.g. a window buffer
+	if len(indexes) > len(messages) {
+		indexes = indexes[len(indexes)-len(messages):]
+	}
+	return w.window(ctx, messages, indexes)
+}
+
+func (w *TokenWindow) window(ctx context.Context, messages []schema.Message, indexes []int) []schema.Message {
+	if w.budget <= 0 || len(messages) == 0 {
+		return messages
+	}
+	budget := w.budget
+	if env := schema.EnvFromContext(ctx); env != nil {
+		budget -= utils.EstimateTokens(env.SOP())
+	}
+
+	kept := make([]bool, len(messages))
+	task, sop := -1, -1
+	for i := range messages {
+		if task < 0 && messages[i].Sender == w.sender {
+			task = i
+		}
+		if messages[i].IsSOP() {
+			sop = i
+		}
+	}
+	for _, i := range []int{task, sop} {
+		if i >= 0 && !kept[i] {
+			kept[i] = true
+			budget -= estimate(messages[i])
+		}
+	}
+	// the most recent messages, up to the first one not fitting, so the
+	// conversation kept has no gap
+	for i := len(messages) - 1; i >= 0; i-- {
+		if kept[i] {
+			continue
+		}
+		cost := estimate(messages[i])
+		if cost > budget {
+			break
+		}
+		kept[i] = true
+		budget -= cost
+	}
+
+	result := make([]schema.Message, 0, len(messages))
+	elided := make([]int, 0)
+	for i, message := range messages {
+		if kept[i] {
+			result = append(result, message)
+		} else {
+			elided = append(elided, i)
+		}
+	}
+	w.report(ctx, messages, elided, indexes)
+	return result
+}
+
+// report tells the handler of the messages elided not told yet.
+func (w *TokenWindow) report(ctx context.Context, messages []schema.Message, elided, indexes []int) {
+	if w.handler == nil || len(elided) == 0 {
+		return
+	}
+	w.mu.Lock()
+	if w.reported == nil {
+		w.reported = make(map[int]bool)
+	}
+	news := make([]schema.Message, 0, len(elided))
+	for _, i := range elided {
+		if len(indexes) == len(messages) {
+			if w.reported[indexes[i]] {
+				continue
+			}
+			w.reported[indexes[i]] = true
+		}
+		news = append(news, messages[i])
+	}
+	w.mu.Unlock()
+	if len(news) != 0 {
+		w.handler.HandleMemoryElided(ctx, news)
+	}
+}
+
+// forget forgets the messages told to the handler, the indexes changing.
+func (w *TokenWindow) forget() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.reported = nil
+}
+
+func (w *TokenWindow) Clear(ctx context.Context) error {
+	w.forget()
+	return w.Memory.Clear(ctx)
+}
+
+// SaveNext inserts msgs in the wrapped memory before the messages not
+// consumed yet, or saves them when it is not a schema.PriorityMemory.
+func (w *TokenWindow) SaveNext(ctx context.Context, msgs ...schema.Message) error {
+	if pm, ok := w.Memory.(schema.PriorityMemory); ok {
+		return pm.SaveNext(ctx, msgs...)
+	}
+	for _, msg := range msgs {
+		if err := w.Memory.Save(ctx, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Snapshot snapshots the wrapped memory, which must be a
+// schema.SnapshotMemory.
+func (w *TokenWindow) Snapshot(ctx context.Context) ([]schema.Message, int, error) {
+	sm, ok := w.Memory.(schema.SnapshotMemory)
+	if !ok {
+		return nil, 0, ErrSnapshotUnsupported
+	}
+	return sm.Snapshot(ctx)
+}
+
+func (w *TokenWindow) Restore(ctx context.Context, messages []schema.Message, consumption int) error {
+	sm, ok := w.Memory.(schema.SnapshotMemory)
+	if !ok {
+		return ErrSnapshotUnsupported
+	}
+	w.forget()
+	return sm.Restore(ctx, messages, consumption)
+}
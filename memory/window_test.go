@@ -0,0 +1,72 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/antgroup/aievo/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type elidedHandler struct {
+	elided [][]schema.Message
+}
+
+func (h *elidedHandler) HandleMemoryElided(_ context.Context, elided []schema.Message) {
+	h.elided = append(h.elided, elided)
+}
+
+func TestTokenWindow(t *testing.T) {
+	ctx := context.Background()
+	buffer := NewBufferMemory()
+	handler := &elidedHandler{}
+	w := NewTokenWindowMemory(buffer, 60, WithElidedHandler(handler))
+
+	task := schema.Message{Type: schema.MsgTypeMsg, Sender: "User", Receiver: "a", Content: "the task"}
+	require.NoError(t, w.Save(ctx, task))
+	require.NoError(t, w.Save(ctx, msg("tiny 1")))
+	require.NoError(t, w.Save(ctx, msg(strings.Repeat("tool output ", 40))))
+	for _, c := range []string{"tiny 2", "tiny 3", "tiny 4"} {
+		require.NoError(t, w.Save(ctx, msg(c)))
+	}
+
+	// 10 tokens for the task, 10 for each tiny message, the huge one does
+	// not fit and stops the window
+	all := func(int, int, schema.Message) bool { return true }
+	assert.Equal(t, []string{"the task", "tiny 2", "tiny 3", "tiny 4"}, contents(w.Load(ctx, all)))
+	require.Len(t, handler.elided, 1)
+	assert.Equal(t, []string{"tiny 1", strings.Repeat("tool output ", 40)}, contents(handler.elided[0]))
+
+	// the messages left out are told once, and the loads without filter
+	// are not windowed
+	assert.Len(t, w.Load(ctx, all), 4)
+	assert.Len(t, w.Load(ctx, nil), 6)
+	assert.Len(t, handler.elided, 1)
+
+	// per receiver, through the filter
+	onlyTiny := func(_, _ int, m schema.Message) bool { return strings.HasPrefix(m.Content, "tiny") }
+	assert.Equal(t, []string{"tiny 1", "tiny 2", "tiny 3", "tiny 4"}, contents(w.Load(ctx, onlyTiny)))
+	assert.Len(t, handler.elided, 1)
+
+	// the SOP is pinned, and the one of the environment is taken from the
+	// budget
+	require.NoError(t, w.Save(ctx, schema.Message{Type: schema.MsgTypeSOP, Sender: "Sop", Content: "the sop"}))
+	assert.Equal(t, []string{"the task", "tiny 2", "tiny 3", "tiny 4", "the sop"}, contents(w.Load(ctx, all)))
+	ctx = schema.ContextWithEnv(ctx, &sopEnv{sop: strings.Repeat("x", 80)})
+	assert.Equal(t, []string{"the task", "tiny 3", "tiny 4", "the sop"}, contents(w.Load(ctx, all)))
+	require.Len(t, handler.elided, 2)
+	assert.Equal(t, []string{"tiny 2"}, contents(handler.elided[1]))
+
+	assert.Len(t, NewTokenWindowMemory(buffer, 0).Load(ctx, all), 7)
+}
+
+type sopEnv struct {
+	schema.Environment
+	sop string
+}
+
+func (e *sopEnv) SOP() string {
+	return e.sop
+}
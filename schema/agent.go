@@ -60,6 +60,14 @@ type Agent interface {
 	Tools() []tool.Tool
 }
 
+// StatefulAgent is implemented by the agents keeping state between their
+// runs in an environment, e.g. the progress on their SOP, for the
+// checkpoints. The environment is the one of ctx.
+type StatefulAgent interface {
+	SaveState(ctx context.Context) ([]byte, error)
+	LoadState(ctx context.Context, state []byte) error
+}
+
 var (
 	ErrMissingLLM          = errors.New("missing field LLM")
 	ErrMissingEnv          = errors.New("missing field Env")
@@ -83,15 +91,16 @@ func ConvertConstructScratchPad(name, self string, messages []Message, steps []S
 		if strings.EqualFold(sender, name) {
 			sender = self
 		}
-		if message.IsMsg() {
+		if message.IsMsg() || message.IsCustom() {
+			prefix := fmt.Sprintf("(%s -> %s)", sender, receiver)
+			// the custom messages are told by their cate, e.g. (a -> b)[vote]
+			if message.IsCustom() {
+				prefix += "[" + strings.ToLower(message.Type) + "]"
+			}
 			if message.Condition != "" {
-				scratchPad += fmt.Sprintf("(%s -> %s)(%s): %s\n",
-					sender, receiver, message.Condition, message.Content)
-			} else {
-				scratchPad += fmt.Sprintf("(%s -> %s): %s\n",
-					sender, receiver, message.Content)
+				prefix += "(" + message.Condition + ")"
 			}
-
+			scratchPad += fmt.Sprintf("%s: %s\n", prefix, message.Content)
 		}
 	}
 	for _, step := range steps {
@@ -0,0 +1,79 @@
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrBlackboardKeyNotFound = errors.New("blackboard key not found")
+	ErrBlackboardVersion     = errors.New("blackboard version conflict")
+)
+
+// BlackboardEntry is a value of a blackboard. Version counts the writes of
+// its key, Author is the agent of the last one.
+type BlackboardEntry struct {
+	Key     string          `json:"key"`
+	Value   json.RawMessage `json:"value"`
+	Version int             `json:"version"`
+	Author  string          `json:"author,omitempty"`
+	Time    time.Time       `json:"time"`
+}
+
+// Blackboard is a key-value state shared by the agents of an environment,
+// the one place for the artifacts they work on together, e.g. an outline
+// or a bug list. The values are JSON.
+type Blackboard interface {
+	Get(key string) (BlackboardEntry, bool)
+	// Set writes value, marshaled to JSON unless it is a json.RawMessage
+	Set(ctx context.Context, key string, value any) (BlackboardEntry, error)
+	// SetIf writes value when the key is at version, 0 for a new key, or
+	// else returns ErrBlackboardVersion
+	SetIf(ctx context.Context, key string, value any, version int) (BlackboardEntry, error)
+	Delete(ctx context.Context, key string) error
+	// List returns the entries ordered by key
+	List() []BlackboardEntry
+}
+
+// BlackboardEnvironment is implemented by the environments with a
+// blackboard.
+type BlackboardEnvironment interface {
+	GetBlackboard() Blackboard
+}
+
+// BlackboardOf returns the blackboard of env, or nil.
+func BlackboardOf(env Environment) Blackboard {
+	if be, ok := env.(BlackboardEnvironment); ok {
+		return be.GetBlackboard()
+	}
+	return nil
+}
+
+// GetBlackboardValue decodes the value of key into a T, with its version.
+func GetBlackboardValue[T any](b Blackboard, key string) (T, int, error) {
+	var value T
+	entry, ok := b.Get(key)
+	if !ok {
+		return value, 0, fmt.Errorf("%w: %s", ErrBlackboardKeyNotFound, key)
+	}
+	if err := json.Unmarshal(entry.Value, &value); err != nil {
+		return value, 0, fmt.Errorf("blackboard key %s: %w", key, err)
+	}
+	return value, entry.Version, nil
+}
+
+func ConvertBlackboard(entries []BlackboardEntry) string {
+	var board strings.Builder
+	for _, entry := range entries {
+		_, _ = fmt.Fprintf(&board, "%s (version %d", entry.Key, entry.Version)
+		if entry.Author != "" {
+			_, _ = fmt.Fprintf(&board, ", by %s", entry.Author)
+		}
+		_, _ = fmt.Fprintf(&board, "): %s\n", entry.Value)
+	}
+	return board.String()
+}
@@ -0,0 +1,43 @@
+package schema
+
+import (
+	"context"
+)
+
+type envKey struct{}
+
+// ContextWithEnv returns a copy of ctx carrying env. Agents shared by
+// several environments, e.g. by the sessions of a team, run in the
+// environment of the context instead of their own.
+func ContextWithEnv(ctx context.Context, env Environment) context.Context {
+	return context.WithValue(ctx, envKey{}, env)
+}
+
+// EnvFromContext returns the environment carried by ctx, or nil.
+func EnvFromContext(ctx context.Context) Environment {
+	env, _ := ctx.Value(envKey{}).(Environment)
+	return env
+}
+
+// EnvOf returns the environment agent runs in: the one of ctx if any, or
+// else the agent's own.
+func EnvOf(ctx context.Context, agent Agent) Environment {
+	if env := EnvFromContext(ctx); env != nil {
+		return env
+	}
+	return agent.Env()
+}
+
+type agentKey struct{}
+
+// ContextWithAgent returns a copy of ctx carrying the name of the agent
+// acting, e.g. for the tools it calls.
+func ContextWithAgent(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, agentKey{}, name)
+}
+
+// AgentFromContext returns the name of the agent acting, or "".
+func AgentFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(agentKey{}).(string)
+	return name
+}
@@ -23,6 +23,14 @@ type Environment interface {
 	GetSubscribeAgents(_ context.Context, subscribed Agent) []Agent
 }
 
+// AgentStateEnvironment is implemented by the environments keeping the
+// state of the agents running in them, e.g. the progress of an agent on
+// its SOP in a session, which goes away with the environment.
+type AgentStateEnvironment interface {
+	AgentState(agent Agent) any
+	SetAgentState(agent Agent, state any)
+}
+
 // Memory is the interface for memory in chains.
 type Memory interface {
 	Load(ctx context.Context, filter func(index, consumption int, message Message) bool) []Message
@@ -35,6 +43,20 @@ type Memory interface {
 	Clear(ctx context.Context) error
 }
 
+// SnapshotMemory is implemented by the memories able to save and restore
+// their messages with the consumption cursor, for the checkpoints.
+type SnapshotMemory interface {
+	Snapshot(ctx context.Context) (messages []Message, consumption int, err error)
+	Restore(ctx context.Context, messages []Message, consumption int) error
+}
+
+// PriorityMemory is implemented by the memories able to put messages ahead
+// of the ones not consumed yet, e.g. to steer a running team.
+type PriorityMemory interface {
+	// SaveNext saves msgs so that they are the next ones loaded by LoadNext
+	SaveNext(ctx context.Context, msgs ...Message) error
+}
+
 const (
 	MsgTypeMsg      = "MSG"
 	MsgTypeCreative = "CREATIVE"
@@ -46,6 +68,19 @@ const (
 	MsgAllReceiver = "ALL"
 )
 
+const (
+	// VisibilityTeam is seen by the receivers and the subscribers of the
+	// sender, the default
+	VisibilityTeam = ""
+	// VisibilityPublic is seen by every member
+	VisibilityPublic = "public"
+	// VisibilityPrivate is seen by the receivers only, e.g. a whisper
+	VisibilityPrivate = "private"
+	// VisibilityRole is seen by the receivers and the members with one of
+	// the roles of the message
+	VisibilityRole = "role"
+)
+
 type MngInfo struct {
 	Create []struct {
 		Name        string   `json:"name"`
@@ -58,7 +93,10 @@ type MngInfo struct {
 }
 
 type Subscribe struct {
+	// Subscribed is the sender of the messages, nil for any sender
 	Subscribed Agent
 	Subscriber Agent
 	Condition  string
+	// Predicate, when set, must also hold for the message
+	Predicate func(message Message) bool
 }
@@ -1,6 +1,7 @@
 package schema
 
 import (
+	"fmt"
 	"strings"
 )
 
@@ -12,10 +13,17 @@ type Message struct {
 	Receiver  string `json:"receiver"`
 	Condition string `json:"condition"`
 	Token     int    `json:"token"`
-	Log       string
+	// Metadata is free structured data about the message, e.g. the amount
+	// of a refund, for the subscriptions to route on
+	Metadata map[string]any `json:"metadata,omitempty"`
+	Log      string         `json:"log,omitempty"`
 	// control msg, to remove and update Agent
-	MngInfo     *MngInfo
-	AllReceiver []string
+	MngInfo     *MngInfo `json:"mng_info,omitempty"`
+	AllReceiver []string `json:"all_receiver,omitempty"`
+	// Visibility is who may see the message besides its sender, Roles the
+	// roles seeing it when it is VisibilityRole
+	Visibility string   `json:"visibility,omitempty"`
+	Roles      []string `json:"roles,omitempty"`
 }
 
 func (m *Message) IsEnd() bool {
@@ -34,6 +42,22 @@ func (m *Message) IsSOP() bool {
 	return strings.EqualFold(m.Type, MsgTypeSOP)
 }
 
+// IsCustom reports whether m is of a domain type rather than a built-in
+// one, e.g. VOTE.
+func (m *Message) IsCustom() bool {
+	return m.Type != "" && !m.IsMsg() && !m.IsEnd() && !m.IsCreative() && !m.IsSOP()
+}
+
+// IsSchedulable reports whether m is delivered to its receivers, i.e. MSG,
+// END, CREATIVE and the custom types marked schedulable in env.
+func (m *Message) IsSchedulable(env Environment) bool {
+	if m.IsMsg() || m.IsEnd() || m.IsCreative() {
+		return true
+	}
+	t, ok := LookupMsgType(env, m.Type)
+	return ok && t.Schedulable
+}
+
 func (m *Message) Receivers() []string {
 	receivers := make([]string, 0)
 	if strings.EqualFold(m.Receiver, MsgAllReceiver) {
@@ -48,3 +72,45 @@ func (m *Message) Receivers() []string {
 	}
 	return receivers
 }
+
+// MsgType is a domain message type, e.g. VOTE or ESCALATE, handled by a
+// strategy of the environment instead of overloading MSG.
+type MsgType struct {
+	// Name is the cate of the messages, case-insensitive
+	Name string
+	// Description tells the agents when to send such messages
+	Description string
+	// Schedulable messages are delivered to their receivers as MSG ones,
+	// the others are only handled by the strategy
+	Schedulable bool
+}
+
+// MsgTypeEnvironment is implemented by the environments with custom
+// message types.
+type MsgTypeEnvironment interface {
+	MsgType(name string) (MsgType, bool)
+}
+
+// MsgTypeAgent is implemented by the agents declaring the custom message
+// types they may send.
+type MsgTypeAgent interface {
+	MsgTypes() []MsgType
+}
+
+// LookupMsgType returns the custom type of the name registered in env.
+func LookupMsgType(env Environment, name string) (MsgType, bool) {
+	me, ok := env.(MsgTypeEnvironment)
+	if !ok {
+		return MsgType{}, false
+	}
+	return me.MsgType(name)
+}
+
+// ConvertMsgTypes renders types for the {{.msg_types}} prompt variable.
+func ConvertMsgTypes(types []MsgType) string {
+	var desc strings.Builder
+	for _, t := range types {
+		_, _ = fmt.Fprintf(&desc, "%s: %s\n", strings.ToLower(t.Name), t.Description)
+	}
+	return desc.String()
+}
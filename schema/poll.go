@@ -0,0 +1,84 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Ballot is the vote of an agent in a poll.
+type Ballot struct {
+	Voter string `json:"voter"`
+	// Choice is the option chosen, Ranking the options ranked by the voter,
+	// the preferred first, in a ranked poll
+	Choice  string   `json:"choice,omitempty"`
+	Ranking []string `json:"ranking,omitempty"`
+	Reason  string   `json:"reason,omitempty"`
+	// Fields are the extra fields asked by the poll, e.g. a confidence
+	Fields map[string]any `json:"fields,omitempty"`
+	// Error is why the ballot is void, e.g. an answer not matching the
+	// ballot schema. A void ballot is not counted
+	Error string `json:"error,omitempty"`
+}
+
+func (b *Ballot) Void() bool {
+	return b.Error != ""
+}
+
+// PollRound is a count of the ballots of a poll, a runoff vote or an
+// elimination of a ranked choice count being a new round.
+type PollRound struct {
+	Options []string       `json:"options"`
+	Counts  map[string]int `json:"counts"`
+	// Eliminated are the options dropped after the round
+	Eliminated []string `json:"eliminated,omitempty"`
+}
+
+// PollResult is the outcome of a poll.
+type PollResult struct {
+	Question    string `json:"question"`
+	Coordinator string `json:"coordinator"`
+	Rule        string `json:"rule"`
+	// Winner is the option decided, empty when the ballots do not decide,
+	// e.g. a tie
+	Winner string      `json:"winner,omitempty"`
+	Rounds []PollRound `json:"rounds"`
+	// Ballots are the ballots of the last vote
+	Ballots []Ballot `json:"ballots"`
+}
+
+func (r *PollResult) Decided() bool {
+	return r.Winner != ""
+}
+
+// ConvertPollResult renders r for the agents, the winner and the ballots.
+func ConvertPollResult(r *PollResult) string {
+	var result strings.Builder
+	_, _ = fmt.Fprintf(&result, "Poll: %s\n", r.Question)
+	if r.Decided() {
+		_, _ = fmt.Fprintf(&result, "Result: %s (%s)\n", r.Winner, r.Rule)
+	} else {
+		_, _ = fmt.Fprintf(&result, "Result: no decision (%s)\n", r.Rule)
+	}
+	if len(r.Rounds) != 0 {
+		last := r.Rounds[len(r.Rounds)-1]
+		counts := make([]string, 0, len(last.Options))
+		for _, option := range last.Options {
+			counts = append(counts, fmt.Sprintf("%s: %d", option, last.Counts[option]))
+		}
+		_, _ = fmt.Fprintf(&result, "Count: %s\n", strings.Join(counts, ", "))
+	}
+	for _, ballot := range r.Ballots {
+		vote := ballot.Choice
+		switch {
+		case ballot.Void():
+			vote = "void"
+		case len(ballot.Ranking) != 0:
+			vote = strings.Join(ballot.Ranking, " > ")
+		}
+		if !ballot.Void() && ballot.Reason != "" {
+			vote += " (" + ballot.Reason + ")"
+		}
+		_, _ = fmt.Fprintf(&result, "- %s: %s\n", ballot.Voter, vote)
+	}
+	return result.String()
+}
@@ -158,7 +158,7 @@ func (api *API) ParseParam(ctx context.Context, m map[string]string) error {
 }
 
 func (api *API) GetRequest(ctx context.Context) (string, error) {
-	req, err := http.NewRequest(http.MethodGet, api.Url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, api.Url, nil)
 	if err != nil {
 		return "", err
 	}
@@ -187,7 +187,7 @@ func (api *API) PostRequest(ctx context.Context) (string, error) {
 	default:
 		return "", ErrUnSupportContent
 	}
-	req, err := http.NewRequest(http.MethodPost, api.Url, reqBody)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, api.Url, reqBody)
 	if err != nil {
 		return "", err
 	}
@@ -3,17 +3,20 @@ package bash
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"io"
 	"os/exec"
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/antgroup/aievo/tool"
 	"github.com/antgroup/aievo/utils/json"
 )
 
+const _waitDelay = time.Second
+
 // platform returns the current operating system in a user-friendly format.
 func platform() string {
 	system := runtime.GOOS
@@ -77,7 +80,7 @@ func (t *Tool) Strict() bool {
 }
 
 // Call executes the provided shell command and returns the output.
-func (t *Tool) Call(_ context.Context, input string) (string, error) {
+func (t *Tool) Call(ctx context.Context, input string) (string, error) {
 	var m map[string]interface{}
 
 	// Parse the input JSON
@@ -131,33 +134,28 @@ func (t *Tool) Call(_ context.Context, input string) (string, error) {
 	var cmd *exec.Cmd
 
 	if runtime.GOOS == "windows" {
-		cmd = exec.Command("cmd", "/c", fullCommand)
+		cmd = exec.CommandContext(ctx, "cmd", "/c", fullCommand)
 	} else {
-		cmd = exec.Command("bash", "-c", fullCommand)
+		cmd = exec.CommandContext(ctx, "bash", "-c", fullCommand)
 	}
+	// kill the children of the shell too when ctx is done, and stop
+	// waiting for the output they may hold
+	killProcessGroup(cmd)
+	cmd.WaitDelay = _waitDelay
 
-	stdoutPipe, _ := cmd.StdoutPipe()
-	stderrPipe, _ := cmd.StderrPipe()
-	defer stdoutPipe.Close()
-	defer stderrPipe.Close()
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
 
 	// execute command
-	err = cmd.Start()
-	if err != nil {
-		return fmt.Sprintf("failed to execute command: %v", err), nil
+	err = cmd.Run()
+	if ctx.Err() != nil {
+		return stdout.String() + stderr.String(), ctx.Err()
 	}
-
-	readPipe := func(pipe io.ReadCloser) (string, error) {
-		var buf bytes.Buffer
-		_, err := io.Copy(&buf, pipe)
-		if err != nil {
-			return "", fmt.Errorf("failed to read from pipe: %v", err)
-		}
-		return buf.String(), nil
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return fmt.Sprintf("failed to execute command: %v", err), nil
 	}
 
-	stdout, _ := readPipe(stdoutPipe)
-	stderr, _ := readPipe(stderrPipe)
-
-	return stdout + stderr, nil
+	return stdout.String() + stderr.String(), nil
 }
@@ -2,9 +2,11 @@ package bash
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"testing"
+	"time"
 )
 
 func TestBash(t *testing.T) {
@@ -18,3 +20,18 @@ func TestBash(t *testing.T) {
 	fmt.Println("output:")
 	fmt.Println(output)
 }
+
+func TestBashCancel(t *testing.T) {
+	shellTool, _ := New()
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	// the background sleep holds the output open after the shell is killed
+	_, err := shellTool.Call(ctx, `{"command": "sleep 10 & sleep 10"}`)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("command is not killed, took %s", elapsed)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package bash
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// killProcessGroup runs cmd in its own process group, which is killed as a
+// whole when the context of cmd is done.
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
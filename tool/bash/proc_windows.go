@@ -0,0 +1,10 @@
+//go:build windows
+
+package bash
+
+import (
+	"os/exec"
+)
+
+// killProcessGroup keeps the default of killing the process only.
+func killProcessGroup(_ *exec.Cmd) {}
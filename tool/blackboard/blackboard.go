@@ -0,0 +1,33 @@
+package blackboard
+
+import (
+	"context"
+	"errors"
+
+	"github.com/antgroup/aievo/schema"
+	"github.com/antgroup/aievo/tool"
+)
+
+var ErrMissingBlackboard = errors.New("no blackboard in the environment")
+
+// GetBlackboardTools returns the tools to get, set and list the entries of
+// board, or of the blackboard of the environment the agent runs in when
+// board is nil.
+func GetBlackboardTools(board schema.Blackboard) []tool.Tool {
+	return []tool.Tool{
+		&Get{Board: board},
+		&Set{Board: board},
+		&List{Board: board},
+	}
+}
+
+// boardOf returns board, or else the blackboard of the environment of ctx.
+func boardOf(ctx context.Context, board schema.Blackboard) (schema.Blackboard, error) {
+	if board != nil {
+		return board, nil
+	}
+	if board = schema.BlackboardOf(schema.EnvFromContext(ctx)); board == nil {
+		return nil, ErrMissingBlackboard
+	}
+	return board, nil
+}
@@ -0,0 +1,49 @@
+package blackboard
+
+import (
+	"context"
+	"testing"
+
+	"github.com/antgroup/aievo/environment"
+	"github.com/antgroup/aievo/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTools(t *testing.T) {
+	env := environment.NewEnv()
+	ctx := schema.ContextWithAgent(schema.ContextWithEnv(context.Background(), env), "writer")
+	tools := GetBlackboardTools(nil)
+	get, set, list := tools[0], tools[1], tools[2]
+
+	out, err := list.Call(ctx, "{}")
+	require.NoError(t, err)
+	assert.Equal(t, "the blackboard is empty", out)
+
+	out, err = set.Call(ctx, `{"key": "bugs", "value": "[\"login fails\"]", "version": 0}`)
+	require.NoError(t, err)
+	assert.Equal(t, "bugs set, now at version 1", out)
+	out, err = set.Call(ctx, `{"key": "title", "value": "A plain title"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "title set, now at version 1", out)
+
+	// a write based on an outdated version is refused
+	out, err = set.Call(ctx, `{"key": "bugs", "value": "[]", "version": 0}`)
+	require.NoError(t, err)
+	assert.Contains(t, out, "bugs is at version 1, not 0")
+
+	out, err = get.Call(ctx, `{"key": "bugs"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "bugs (version 1, by writer): [\"login fails\"]\n", out)
+	out, err = list.Call(ctx, "{}")
+	require.NoError(t, err)
+	assert.Equal(t, "bugs (version 1, by writer): [\"login fails\"]\ntitle (version 1, by writer): \"A plain title\"\n", out)
+
+	bugs, version, err := schema.GetBlackboardValue[[]string](env.Blackboard, "bugs")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"login fails"}, bugs)
+	assert.Equal(t, 1, version)
+
+	_, err = list.Call(context.Background(), "{}")
+	assert.ErrorIs(t, err, ErrMissingBlackboard)
+}
@@ -0,0 +1,61 @@
+package blackboard
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/antgroup/aievo/schema"
+	"github.com/antgroup/aievo/tool"
+	"github.com/antgroup/aievo/utils/json"
+)
+
+type Get struct {
+	Board schema.Blackboard
+}
+
+var _ tool.Tool = &Get{}
+
+func (t *Get) Name() string {
+	return "blackboard_get"
+}
+
+func (t *Get) Description() string {
+	bytes, _ := json.Marshal(t.Schema())
+	return fmt.Sprintf("Get the value of a key of the blackboard shared by the team, with its version, the input must be json schema: %s", string(bytes)) + `
+Example Input: {"key": "outline"}`
+}
+
+func (t *Get) Schema() *tool.PropertiesSchema {
+	return &tool.PropertiesSchema{
+		Type: tool.TypeJson,
+		Properties: map[string]tool.PropertySchema{
+			"key": {
+				Type:        tool.TypeString,
+				Description: "The key to get",
+			},
+		},
+		Required: []string{"key"},
+	}
+}
+
+func (t *Get) Strict() bool {
+	return true
+}
+
+func (t *Get) Call(ctx context.Context, input string) (string, error) {
+	var param struct {
+		Key string `json:"key"`
+	}
+	if err := json.Unmarshal([]byte(input), &param); err != nil {
+		return "json unmarshal error, please try again", nil
+	}
+	board, err := boardOf(ctx, t.Board)
+	if err != nil {
+		return "", err
+	}
+	entry, ok := board.Get(param.Key)
+	if !ok {
+		return fmt.Sprintf("key %s is not on the blackboard", param.Key), nil
+	}
+	return schema.ConvertBlackboard([]schema.BlackboardEntry{entry}), nil
+}
@@ -0,0 +1,47 @@
+package blackboard
+
+import (
+	"context"
+
+	"github.com/antgroup/aievo/schema"
+	"github.com/antgroup/aievo/tool"
+)
+
+type List struct {
+	Board schema.Blackboard
+}
+
+var _ tool.Tool = &List{}
+
+func (t *List) Name() string {
+	return "blackboard_list"
+}
+
+func (t *List) Description() string {
+	return `List the entries of the blackboard shared by the team, with their versions and values, the input must be an empty json object.
+Example Input: {}`
+}
+
+func (t *List) Schema() *tool.PropertiesSchema {
+	return &tool.PropertiesSchema{
+		Type:       tool.TypeJson,
+		Properties: map[string]tool.PropertySchema{},
+		Required:   []string{},
+	}
+}
+
+func (t *List) Strict() bool {
+	return true
+}
+
+func (t *List) Call(ctx context.Context, _ string) (string, error) {
+	board, err := boardOf(ctx, t.Board)
+	if err != nil {
+		return "", err
+	}
+	entries := board.List()
+	if len(entries) == 0 {
+		return "the blackboard is empty", nil
+	}
+	return schema.ConvertBlackboard(entries), nil
+}
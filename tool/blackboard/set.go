@@ -0,0 +1,89 @@
+package blackboard
+
+import (
+	"context"
+	stdjson "encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/antgroup/aievo/schema"
+	"github.com/antgroup/aievo/tool"
+	"github.com/antgroup/aievo/utils/json"
+)
+
+type Set struct {
+	Board schema.Blackboard
+}
+
+var _ tool.Tool = &Set{}
+
+func (t *Set) Name() string {
+	return "blackboard_set"
+}
+
+func (t *Set) Description() string {
+	bytes, _ := json.Marshal(t.Schema())
+	return fmt.Sprintf("Set the value of a key of the blackboard shared by the team. Give the version you read to make sure nobody changed it since, 0 for a new key. The input must be json schema: %s", string(bytes)) + `
+Example Input: {"key": "bugs", "value": "[\"login fails\", \"typo on home page\"]", "version": 2}`
+}
+
+func (t *Set) Schema() *tool.PropertiesSchema {
+	return &tool.PropertiesSchema{
+		Type: tool.TypeJson,
+		Properties: map[string]tool.PropertySchema{
+			"key": {
+				Type:        tool.TypeString,
+				Description: "The key to set",
+			},
+			"value": {
+				Type:        tool.TypeString,
+				Description: "The new value, as JSON, e.g. a list or an object; a plain text is kept as a string",
+			},
+			"version": {
+				Type:        tool.TypeInt,
+				Description: "The version of the key the new value is based on, omit it to overwrite whatever the version",
+			},
+		},
+		Required: []string{"key", "value"},
+	}
+}
+
+func (t *Set) Strict() bool {
+	return false
+}
+
+func (t *Set) Call(ctx context.Context, input string) (string, error) {
+	var param struct {
+		Key     string `json:"key"`
+		Value   any    `json:"value"`
+		Version *int   `json:"version"`
+	}
+	if err := json.Unmarshal([]byte(input), &param); err != nil {
+		return "json unmarshal error, please try again", nil
+	}
+	board, err := boardOf(ctx, t.Board)
+	if err != nil {
+		return "", err
+	}
+
+	// the value is JSON given as a string, or given as is
+	value := param.Value
+	if s, ok := value.(string); ok && stdjson.Valid([]byte(s)) {
+		value = stdjson.RawMessage(s)
+	}
+	var entry schema.BlackboardEntry
+	if param.Version != nil {
+		entry, err = board.SetIf(ctx, param.Key, value, *param.Version)
+	} else {
+		entry, err = board.Set(ctx, param.Key, value)
+	}
+	if errors.Is(err, schema.ErrBlackboardVersion) {
+		current, _ := board.Get(param.Key)
+		return fmt.Sprintf("%s, get it again and retry on the current value:\n%s",
+			err.Error(), schema.ConvertBlackboard([]schema.BlackboardEntry{current})), nil
+	}
+	if err != nil {
+		return fmt.Sprintf("failed to set %s: %v", param.Key, err), nil
+	}
+	return fmt.Sprintf("%s set, now at version %d", entry.Key, entry.Version), nil
+}
@@ -1,6 +1,7 @@
 package code
 
 import (
+	"context"
 	"errors"
 	"os"
 	"os/exec"
@@ -17,9 +18,9 @@ func NewGolangRunner() Runner {
 }
 
 // CheckRuntime checks if the Go runtime environment is installed and accessible
-func (r *GolangRunner) CheckRuntime() error {
+func (r *GolangRunner) CheckRuntime(ctx context.Context) error {
 	// Try to execute "go version" to check if Go is installed
-	cmd := exec.Command("go", "version")
+	cmd := exec.CommandContext(ctx, "go", "version")
 	output, err := cmd.Output()
 
 	if err != nil {
@@ -37,7 +38,7 @@ func (r *GolangRunner) CheckRuntime() error {
 }
 
 // Run compiles and executes the Go code with the provided arguments using "go run"
-func (r *GolangRunner) Run(code string, args []string) (string, error) {
+func (r *GolangRunner) Run(ctx context.Context, code string, args []string) (string, error) {
 	// Create a temporary directory for the Go files
 	tmpDir, err := os.MkdirTemp("", "golang_runner")
 	if err != nil {
@@ -61,7 +62,7 @@ func (r *GolangRunner) Run(code string, args []string) (string, error) {
 	}
 
 	// Run the Go code using "go run" and pass arguments
-	cmd := exec.Command("go", append([]string{"run", goFilePath}, args...)...)
+	cmd := exec.CommandContext(ctx, "go", append([]string{"run", goFilePath}, args...)...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return string(output), errors.New("error during execution: " + err.Error())
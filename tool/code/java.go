@@ -1,6 +1,7 @@
 package code
 
 import (
+	"context"
 	"errors"
 	"os"
 	"os/exec"
@@ -18,9 +19,9 @@ func NewJavaRunner() Runner {
 }
 
 // CheckRuntime checks if the Java runtime environment is installed and accessible
-func (r *JavaRunner) CheckRuntime() error {
+func (r *JavaRunner) CheckRuntime(ctx context.Context) error {
 	// Try to execute "java -version" to check if Java is installed
-	cmd := exec.Command("java", "-version")
+	cmd := exec.CommandContext(ctx, "java", "-version")
 	output, err := cmd.CombinedOutput() // java -version outputs to stderr
 
 	if err != nil {
@@ -38,7 +39,7 @@ func (r *JavaRunner) CheckRuntime() error {
 }
 
 // Run compiles and executes the provided Java code with the provided arguments using "javac" and "java"
-func (r *JavaRunner) Run(code string, args []string) (string, error) {
+func (r *JavaRunner) Run(ctx context.Context, code string, args []string) (string, error) {
 	// Extract the class name from the Java code using a regular expression
 	className, err := extractClassName(code)
 	if err != nil {
@@ -68,14 +69,14 @@ func (r *JavaRunner) Run(code string, args []string) (string, error) {
 	}
 
 	// Compile the Java code using "javac" inside the temporary directory
-	compileCmd := exec.Command("javac", javaFilePath)
+	compileCmd := exec.CommandContext(ctx, "javac", javaFilePath)
 	compileOutput, err := compileCmd.CombinedOutput()
 	if err != nil {
 		return string(compileOutput), errors.New("error during compilation: " + err.Error())
 	}
 
 	// Run the compiled Java class using "java" from the temporary directory
-	runCmd := exec.Command("java", "-cp", tmpDir, className)
+	runCmd := exec.CommandContext(ctx, "java", "-cp", tmpDir, className)
 	runCmd.Args = append(runCmd.Args, args...)
 	runOutput, err := runCmd.CombinedOutput()
 	if err != nil {
@@ -1,6 +1,7 @@
 package code
 
 import (
+	"context"
 	"errors"
 	"os"
 	"os/exec"
@@ -17,9 +18,9 @@ func NewPythonRunner() Runner {
 }
 
 // CheckRuntime checks if the Python runtime environment is installed and accessible
-func (r *PythonRunner) CheckRuntime() error {
+func (r *PythonRunner) CheckRuntime(ctx context.Context) error {
 	// Try to execute "python3 --version" to check if Python is installed
-	cmd := exec.Command("python3", "--version")
+	cmd := exec.CommandContext(ctx, "python3", "--version")
 	output, err := cmd.Output()
 
 	if err != nil {
@@ -37,7 +38,7 @@ func (r *PythonRunner) CheckRuntime() error {
 }
 
 // Run executes the provided Python code with the provided arguments using "python3"
-func (r *PythonRunner) Run(code string, args []string) (string, error) {
+func (r *PythonRunner) Run(ctx context.Context, code string, args []string) (string, error) {
 	// Create a temporary directory for the Python file
 	tmpDir, err := os.MkdirTemp("", "python_runner")
 	if err != nil {
@@ -61,7 +62,7 @@ func (r *PythonRunner) Run(code string, args []string) (string, error) {
 	}
 
 	// Run the Python code using "python3" and pass arguments
-	cmd := exec.Command("python3", append([]string{pyFilePath}, args...)...)
+	cmd := exec.CommandContext(ctx, "python3", append([]string{pyFilePath}, args...)...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return string(output), errors.New("error during execution: " + err.Error())
@@ -72,7 +72,7 @@ func (t *Tool) Strict() bool {
 }
 
 // Call runs the tool with the given input
-func (t *Tool) Call(_ context.Context, input string) (string, error) {
+func (t *Tool) Call(ctx context.Context, input string) (string, error) {
 	var m map[string]interface{}
 
 	err := json.Unmarshal([]byte(input), &m)
@@ -85,13 +85,16 @@ func (t *Tool) Call(_ context.Context, input string) (string, error) {
 	}
 
 	// check program runtime
-	err = t.runner.CheckRuntime()
+	err = t.runner.CheckRuntime(ctx)
 	if err != nil {
 		return err.Error(), nil
 	}
 
 	// run code
-	result, err := t.runner.Run(m["code"].(string), nil)
+	result, err := t.runner.Run(ctx, m["code"].(string), nil)
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
 	if err != nil {
 		return err.Error(), nil
 	}
@@ -1,11 +1,13 @@
 package code
 
+import "context"
+
 type Runner interface {
 	// CheckRuntime check program language runtime
-	CheckRuntime() error
-	// Run execute code
+	CheckRuntime(ctx context.Context) error
+	// Run execute code, the process is killed when ctx is done
 	// for example: go run code 1 2 3
-	Run(code string, args []string) (string, error)
+	Run(ctx context.Context, code string, args []string) (string, error)
 }
 
 type RunnerFactory func() Runner
@@ -11,10 +11,14 @@ import (
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
+const _refreshInterval = 15 * time.Second
+
 func New(ctx context.Context, name string, param *ServerParam) (*Client, error) {
 	c := &Client{
-		name:  name,
-		param: param,
+		name:   name,
+		param:  param,
+		ctx:    ctx,
+		closed: make(chan struct{}),
 	}
 	var mcpClient client.MCPClient
 	var err error
@@ -90,7 +94,7 @@ func (c *Client) initSSEClient(ctx context.Context) (client.MCPClient, error) {
 func (c *Client) ListTools(ctx context.Context) ([]mcp.Tool, error) {
 	var err error
 	toolsRequest := mcp.ListToolsRequest{}
-	result, err := c.c.ListTools(ctx, toolsRequest)
+	result, err := c.client().ListTools(ctx, toolsRequest)
 	if err != nil {
 		log.Printf("failed to list tools: %v", err)
 		return nil, err
@@ -110,7 +114,7 @@ func (c *Client) CallTool(ctx context.Context, name, input string) (*mcp.CallToo
 	request.Params.Name = name
 	request.Params.Arguments = param
 
-	result, err := c.c.CallTool(ctx, request)
+	result, err := c.client().CallTool(ctx, request)
 	if err != nil {
 		log.Printf("failed to call tool: %v", err)
 		return nil, err
@@ -120,12 +124,28 @@ func (c *Client) CallTool(ctx context.Context, name, input string) (*mcp.CallToo
 
 }
 
+func (c *Client) client() client.MCPClient {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.c
+}
+
+// refresh pings the server every 15s and reconnects when it does not
+// answer, until the client is closed or its context done.
 func (c *Client) refresh() {
-	tick := time.Tick(time.Second * 15)
+	tick := time.NewTicker(_refreshInterval)
+	defer tick.Stop()
 	for {
-		<-tick
-		ctx := context.Background()
-		err := c.c.Ping(ctx)
+		select {
+		case <-tick.C:
+		case <-c.closed:
+			return
+		case <-c.ctx.Done():
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.ctx, _refreshInterval)
+		err := c.client().Ping(ctx)
+		cancel()
 		if err == nil {
 			continue
 		}
@@ -133,21 +153,38 @@ func (c *Client) refresh() {
 		var mcpClient client.MCPClient
 		switch c.param.TransportType {
 		case TransportTypeSSE:
-			mcpClient, err = c.initSSEClient(ctx)
+			mcpClient, err = c.initSSEClient(c.ctx)
 		case TransportTypeStdio:
-			mcpClient, err = c.initStdioClient(ctx)
+			mcpClient, err = c.initStdioClient(c.ctx)
 		}
 		if err != nil {
 			log.Printf("failed to initialize mcp client: %v, try again after 15s", err)
 			continue
 		}
+		c.mu.Lock()
+		select {
+		case <-c.closed:
+			// closed while reconnecting, the new client is not used
+			c.mu.Unlock()
+			mcpClient.Close()
+			return
+		default:
+		}
 		oldClient := c.c
 		c.c = mcpClient
+		c.mu.Unlock()
 		oldClient.Close()
 	}
 }
 
 func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case <-c.closed:
+	default:
+		close(c.closed)
+	}
 	if c.c != nil {
 		return c.c.Close()
 	}
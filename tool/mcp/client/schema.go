@@ -1,6 +1,9 @@
 package client
 
 import (
+	"context"
+	"sync"
+
 	"github.com/mark3labs/mcp-go/client"
 )
 
@@ -33,4 +36,8 @@ type Client struct {
 	param *ServerParam
 	// for sse client, init when use it
 	c client.MCPClient
+	// ctx is the one the client was created with, closed stops the refresh
+	ctx    context.Context
+	closed chan struct{}
+	mu     sync.RWMutex
 }
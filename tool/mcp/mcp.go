@@ -21,7 +21,12 @@ var _ tool.Tool = Tool{}
 
 // New initializes mcp clients from parse schema
 func New(schema string) ([]tool.Tool, error) {
-	ctx := context.Background()
+	return NewWithContext(context.Background(), schema)
+}
+
+// NewWithContext is New bounded by ctx, which the connections to the
+// servers must not outlive, e.g. the SSE streams.
+func NewWithContext(ctx context.Context, schema string) ([]tool.Tool, error) {
 	mcpServers, err := ParseMcpServers(ctx, schema)
 	if err != nil {
 		return nil, err
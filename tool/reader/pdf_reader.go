@@ -1,6 +1,7 @@
 package reader
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"os"
@@ -18,15 +19,19 @@ func NewPdfReader() Reader {
 	return &PdfReader{}
 }
 
-func (r *PdfReader) Read(url string) (string, error) {
+func (r *PdfReader) Read(ctx context.Context, url string) (string, error) {
 	// check poppler version
-	err := pdf.CheckPopplerVersion()
+	err := pdf.CheckPopplerVersion(ctx)
 	if err != nil {
 		return err.Error(), nil
 	}
 
 	// download pdf file
-	resp, err := http.Get(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return "", err
 	}
@@ -48,7 +53,7 @@ func (r *PdfReader) Read(url string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	pages, err := pdf.ExtractOrError(bytes)
+	pages, err := pdf.ExtractOrError(ctx, bytes)
 	if err != nil {
 		return "", err
 	}
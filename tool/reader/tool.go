@@ -71,7 +71,7 @@ func (t *Tool) Strict() bool {
 	return true
 }
 
-func (t *Tool) Call(_ context.Context, input string) (string, error) {
+func (t *Tool) Call(ctx context.Context, input string) (string, error) {
 	var param ReadParam
 
 	err := json.Unmarshal([]byte(input), &param)
@@ -79,7 +79,10 @@ func (t *Tool) Call(_ context.Context, input string) (string, error) {
 		return "json unmarshal error, please try again", nil
 	}
 
-	text, err := t.Reader.Read(param.Url)
+	text, err := t.Reader.Read(ctx, param.Url)
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
 	if err != nil {
 		return err.Error(), nil
 	}
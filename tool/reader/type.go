@@ -1,7 +1,9 @@
 package reader
 
+import "context"
+
 type Reader interface {
-	Read(url string) (string, error)
+	Read(ctx context.Context, url string) (string, error)
 }
 
 type Factory func() Reader
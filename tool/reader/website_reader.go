@@ -1,11 +1,19 @@
 package reader
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	nurl "net/url"
+	"strings"
 	"time"
 
 	"github.com/go-shiori/go-readability"
 )
 
+const _websiteTimeout = 30 * time.Second
+
 type WebsiteReader struct {
 }
 
@@ -15,8 +23,27 @@ func NewWebsiteReader() Reader {
 	return &WebsiteReader{}
 }
 
-func (r *WebsiteReader) Read(url string) (string, error) {
-	article, err := readability.FromURL(url, 30*time.Second)
+func (r *WebsiteReader) Read(ctx context.Context, url string) (string, error) {
+	parsedURL, err := nurl.ParseRequestURI(url)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse URL: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, _websiteTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch the page: %w", err)
+	}
+	defer resp.Body.Close()
+	if !strings.Contains(resp.Header.Get("Content-Type"), "text/html") {
+		return "", errors.New("URL is not a HTML document")
+	}
+
+	article, err := readability.FromReader(resp.Body, parsedURL)
 	if err != nil {
 		return "", err
 	}
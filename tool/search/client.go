@@ -1,6 +1,7 @@
 package search
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
@@ -43,7 +44,7 @@ func NewSearch(engine string,
 }
 
 // Search get content from search engine
-func (client *Client) Search(input string, topK int) (string, error) {
+func (client *Client) Search(ctx context.Context, input string, topK int) (string, error) {
 	if client.SearchParamsHandler == nil {
 		return "", ErrorNoSearchParamsHandler
 	}
@@ -55,7 +56,7 @@ func (client *Client) Search(input string, topK int) (string, error) {
 	pageSize := topK
 	for len(datas) < topK {
 		params, _ := client.SearchParamsHandler.Handle(input, pageIndex, pageSize)
-		rsp, err := client.execute(params, "/search", "json")
+		rsp, err := client.execute(ctx, params, "/search", "json")
 		if err != nil {
 			return "", err
 		}
@@ -75,7 +76,7 @@ func (client *Client) Search(input string, topK int) (string, error) {
 }
 
 // execute HTTP get request and returns http response
-func (client *Client) execute(params map[string]string, path string, output string) (*http.Response, error) {
+func (client *Client) execute(ctx context.Context, params map[string]string, path string, output string) (*http.Response, error) {
 	query := url.Values{}
 	if params != nil {
 		for k, v := range params {
@@ -100,7 +101,11 @@ func (client *Client) execute(params map[string]string, path string, output stri
 	query.Add("output", output)
 
 	endpoint := _baseUrl + path + "?" + query.Encode()
-	rsp, err := client.HttpSearch.Get(endpoint)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	rsp, err := client.HttpSearch.Do(req)
 	if err != nil {
 		return nil, err
 	}
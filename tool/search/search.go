@@ -76,7 +76,7 @@ func (t *Tool) Strict() bool {
 	return true
 }
 
-func (t *Tool) Call(_ context.Context, input string) (string, error) {
+func (t *Tool) Call(ctx context.Context, input string) (string, error) {
 	var m map[string]interface{}
 
 	err := json.Unmarshal([]byte(input), &m)
@@ -88,7 +88,10 @@ func (t *Tool) Call(_ context.Context, input string) (string, error) {
 		return "query is required", nil
 	}
 
-	ret, err := t.client.Search(m["query"].(string), t.TopK)
+	ret, err := t.client.Search(ctx, m["query"].(string), t.TopK)
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
 	if err != nil {
 		return "Query Search Engine Error, Please Try Again", nil
 	}
@@ -0,0 +1,126 @@
+package tool
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+)
+
+var (
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+)
+
+// Validate checks the JSON document data against the schema, and returns
+// all the violations found, each one prefixed with its path.
+func (s *PropertiesSchema) Validate(data []byte) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("$: invalid json: %w", err)
+	}
+	return s.ValidateValue(v)
+}
+
+// ValidateValue is like Validate for a decoded JSON value.
+func (s *PropertiesSchema) ValidateValue(v any) error {
+	root := PropertySchema{
+		Type:       s.Type,
+		Properties: s.Properties,
+		Required:   s.Required,
+	}
+	return errors.Join(root.validate("$", v)...)
+}
+
+func (p *PropertySchema) validate(path string, v any) []error {
+	if len(p.OneOf) > 0 {
+		for _, one := range p.OneOf {
+			if one.ValidateValue(v) == nil {
+				return nil
+			}
+		}
+		return []error{fmt.Errorf("%s: does not match any of the schemas", path)}
+	}
+	if p.Type != "" && !matchType(p.Type, v) {
+		return []error{fmt.Errorf("%s: expect %s, got %s", path, p.Type, typeOf(v))}
+	}
+	errs := make([]error, 0)
+	if len(p.Enum) > 0 {
+		s, ok := v.(string)
+		if !ok || !slices.Contains(p.Enum, s) {
+			errs = append(errs, fmt.Errorf("%s: must be one of %v", path, p.Enum))
+		}
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		for _, name := range p.Required {
+			if _, ok := v[name]; !ok {
+				errs = append(errs, fmt.Errorf("%s: missing required property %q", path, name))
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop, ok := p.Properties[name]
+			if ok {
+				errs = append(errs, prop.validate(path+"."+name, v[name])...)
+			}
+		}
+	case []any:
+		if p.Items != nil {
+			for i, item := range v {
+				errs = append(errs, p.Items.validate(fmt.Sprintf("%s[%d]", path, i), item)...)
+			}
+		}
+	}
+	return errs
+}
+
+func matchType(t string, v any) bool {
+	switch t {
+	case TypeJson:
+		_, ok := v.(map[string]any)
+		return ok
+	case TypeArr:
+		_, ok := v.([]any)
+		return ok
+	case TypeString:
+		_, ok := v.(string)
+		return ok
+	case TypeNumber:
+		_, ok := v.(float64)
+		return ok
+	case TypeInt:
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	case TypeBoolean:
+		_, ok := v.(bool)
+		return ok
+	case "null":
+		return v == nil
+	}
+	return true
+}
+
+func typeOf(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return TypeJson
+	case []any:
+		return TypeArr
+	case string:
+		return TypeString
+	case float64:
+		return TypeNumber
+	case bool:
+		return TypeBoolean
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,34 @@
+package tool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	schema := &PropertiesSchema{
+		Type: TypeJson,
+		Properties: map[string]PropertySchema{
+			"name":  {Type: TypeString},
+			"level": {Type: TypeString, Enum: []string{"low", "high"}},
+			"tags":  {Type: TypeArr, Items: &PropertySchema{Type: TypeString}},
+			"owner": {
+				Type:       TypeJson,
+				Properties: map[string]PropertySchema{"age": {Type: TypeInt}},
+				Required:   []string{"age"},
+			},
+		},
+		Required: []string{"name"},
+	}
+	assert.NoError(t, schema.Validate([]byte(`{"name": "a", "level": "low", "tags": ["x"], "owner": {"age": 3}}`)))
+
+	err := schema.Validate([]byte(`{"level": "mid", "tags": ["x", 1], "owner": {"age": 3.5}}`))
+	assert.EqualError(t, err, `$: missing required property "name"
+$.level: must be one of [low high]
+$.owner.age: expect integer, got number
+$.tags[1]: expect string, got number`)
+
+	assert.EqualError(t, schema.Validate([]byte(`[]`)), "$: expect object, got array")
+	assert.Error(t, schema.Validate([]byte(`{`)))
+}
@@ -0,0 +1,108 @@
+package transcript
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+
+	"github.com/antgroup/aievo/callback"
+)
+
+// Markdown renders the events as a Markdown document. The prompts and the
+// outputs of the LLM, which are long, are folded.
+func Markdown(w io.Writer, events []callback.Event) error {
+	var b strings.Builder
+	b.WriteString("# Transcript\n")
+	for _, event := range events {
+		// delivered and kept messages were already rendered when sent
+		if event.Type == callback.EventMessageOut || event.Type == callback.EventHistory {
+			continue
+		}
+		title, body := Title(event), Body(event)
+		switch event.Type {
+		case callback.EventAgentStart:
+			fmt.Fprintf(&b, "\n## %s\n", event.Agent)
+			fmt.Fprintf(&b, "\n_%s_ %s\n", event.Time.Format("15:04:05"), title)
+			continue
+		case callback.EventLLMStart, callback.EventLLMEnd, callback.EventSOP,
+			callback.EventRetrieverEnd:
+			fmt.Fprintf(&b, "\n<details><summary>%s</summary>\n\n%s\n</details>\n",
+				template.HTMLEscapeString(title), fence(body))
+			continue
+		}
+		fmt.Fprintf(&b, "\n_%s_ **%s**\n", event.Time.Format("15:04:05"), title)
+		if body != "" {
+			fmt.Fprintf(&b, "\n%s\n", quote(body))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func fence(s string) string {
+	marker := "```"
+	for strings.Contains(s, marker) {
+		marker += "`"
+	}
+	return marker + "\n" + s + "\n" + marker
+}
+
+func quote(s string) string {
+	return "> " + strings.ReplaceAll(s, "\n", "\n> ")
+}
+
+var _html = template.Must(template.New("transcript").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Transcript</title>
+<style>
+body { font-family: sans-serif; max-width: 960px; margin: auto; }
+.event { border-left: 4px solid #ccc; margin: 8px 0; padding: 4px 8px; }
+.message_in { border-color: #4a90d9; }
+.agent_start, .agent_end { border-color: #7b5ea7; }
+.action_start, .action_end { border-color: #e0a030; }
+.feedback { border-color: #d9534f; }
+.time { color: #888; font-size: small; }
+pre { white-space: pre-wrap; background: #f6f6f6; padding: 6px; }
+</style>
+</head>
+<body>
+<h1>Transcript</h1>
+{{range .}}<div class="event {{.Type}}">
+<span class="time">#{{.Seq}} {{.Time}}</span> <b>{{.Title}}</b>
+{{if .Body}}{{if .Fold}}<details><summary>show</summary><pre>{{.Body}}</pre></details>{{else}}<pre>{{.Body}}</pre>{{end}}{{end}}
+</div>
+{{end}}</body>
+</html>
+`))
+
+type htmlEvent struct {
+	Seq   int
+	Time  string
+	Type  callback.EventType
+	Title string
+	Body  string
+	Fold  bool
+}
+
+// HTML renders the events as a standalone HTML page.
+func HTML(w io.Writer, events []callback.Event) error {
+	items := make([]htmlEvent, 0, len(events))
+	for _, event := range events {
+		if event.Type == callback.EventMessageOut || event.Type == callback.EventHistory {
+			continue
+		}
+		items = append(items, htmlEvent{
+			Seq:   event.Seq,
+			Time:  event.Time.Format("15:04:05.000"),
+			Type:  event.Type,
+			Title: Title(event),
+			Body:  Body(event),
+			Fold: event.Type == callback.EventLLMStart || event.Type == callback.EventLLMEnd ||
+				event.Type == callback.EventSOP,
+		})
+	}
+	return _html.Execute(w, items)
+}
@@ -0,0 +1,57 @@
+package transcript
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/antgroup/aievo/callback"
+)
+
+// Replay steps through the events in a terminal, printing one event to out
+// for each line read from in. An empty line shows the next event, "c"
+// shows all the remaining ones, "s" skips to the next message sent, "b"
+// goes back one event and "q" quits.
+func Replay(in io.Reader, out io.Writer, events []callback.Event) error {
+	scanner := bufio.NewScanner(in)
+	for i := 0; i < len(events); {
+		printEvent(out, events[i])
+		if _, err := fmt.Fprint(out, "[enter] next, [s] next message, [b] back, [c] continue, [q] quit: "); err != nil {
+			return err
+		}
+		command := "q"
+		if scanner.Scan() {
+			command = strings.TrimSpace(scanner.Text())
+		}
+		switch command {
+		case "q":
+			return scanner.Err()
+		case "c":
+			for _, event := range events[i+1:] {
+				printEvent(out, event)
+			}
+			return nil
+		case "b":
+			if i > 0 {
+				i--
+			}
+		case "s":
+			i++
+			for i < len(events)-1 && events[i].Type != callback.EventMessageIn {
+				i++
+			}
+		default:
+			i++
+		}
+	}
+	return nil
+}
+
+func printEvent(out io.Writer, event callback.Event) {
+	_, _ = fmt.Fprintf(out, "\n#%d %s %s\n", event.Seq,
+		event.Time.Format("15:04:05"), Title(event))
+	if body := Body(event); body != "" {
+		_, _ = fmt.Fprintln(out, body)
+	}
+}
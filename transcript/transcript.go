@@ -0,0 +1,213 @@
+// Package transcript loads the event logs written by callback.Recorder and
+// renders them, to review a run after the fact.
+package transcript
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/antgroup/aievo/callback"
+	"github.com/antgroup/aievo/schema"
+)
+
+const _maxLine = 64 << 20
+
+// Load reads the events of a log, one JSON object per line.
+func Load(r io.Reader) ([]callback.Event, error) {
+	events := make([]callback.Event, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), _maxLine)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var event callback.Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
+
+func LoadFile(path string) ([]callback.Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+// Messages returns the messages kept in memory at the end of the last run
+// of the log, in order, e.g. to fork the run with aievo.Fork. A log without
+// them, e.g. of a run which crashed, gives the messages queued instead,
+// which may hold ones never kept or be in another order.
+func Messages(events []callback.Event) []schema.Message {
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].Type == callback.EventHistory {
+			return append([]schema.Message{}, events[i].Messages...)
+		}
+	}
+	messages := make([]schema.Message, 0)
+	for _, event := range events {
+		if event.Type != callback.EventMessageIn || event.Message == nil ||
+			event.Message.IsSOP() {
+			continue
+		}
+		messages = append(messages, *event.Message)
+	}
+	return messages
+}
+
+// Title is a one line summary of event.
+func Title(event callback.Event) string {
+	switch event.Type {
+	case callback.EventMessageIn, callback.EventMessageOut:
+		if event.Message == nil {
+			break
+		}
+		what := "a message"
+		switch {
+		case event.Message.IsEnd():
+			what = "the final answer"
+		case event.Message.IsSOP():
+			what = "the SOP"
+		case event.Message.IsCreative():
+			what = "a team change"
+		case event.Message.IsCustom():
+			what = "a " + strings.ToLower(event.Message.Type) + " message"
+		}
+		if event.Type == callback.EventMessageOut {
+			return fmt.Sprintf("%s of %s delivered to %s", what,
+				event.Message.Sender, receiver(event.Message))
+		}
+		return fmt.Sprintf("%s sent %s to %s", event.Message.Sender, what,
+			receiver(event.Message))
+	case callback.EventAgentStart:
+		return fmt.Sprintf("%s starts with %d messages", event.Agent, len(event.Messages))
+	case callback.EventAgentEnd:
+		return fmt.Sprintf("%s ends with %d messages, %d tokens",
+			event.Agent, len(event.Messages), event.Tokens)
+	case callback.EventActionStart, callback.EventActionEnd:
+		if event.Action == nil {
+			break
+		}
+		if event.Type == callback.EventActionStart {
+			return fmt.Sprintf("%s calls %s", event.Agent, event.Action.Action)
+		}
+		return fmt.Sprintf("%s got the result of %s", event.Agent, event.Action.Action)
+	case callback.EventLLMStart:
+		return "LLM prompt"
+	case callback.EventLLMEnd:
+		return fmt.Sprintf("LLM output, %d tokens", event.Tokens)
+	case callback.EventSOP:
+		return "SOP updated"
+	case callback.EventRetrieverStart:
+		return fmt.Sprintf("retrieve %q", event.Query)
+	case callback.EventRetrieverEnd:
+		return fmt.Sprintf("retrieved %d documents for %q", len(event.Documents), event.Query)
+	case callback.EventFeedback:
+		return fmt.Sprintf("feedback on %s: %s", event.Agent, event.Verdict)
+	case callback.EventMemberJoin:
+		return fmt.Sprintf("%s joined", event.Agent)
+	case callback.EventMemberLeave:
+		return fmt.Sprintf("%s left", event.Agent)
+	case callback.EventMemberReplace:
+		return fmt.Sprintf("%s replaced %s", event.Agent, event.Old)
+	case callback.EventMemoryElided:
+		return fmt.Sprintf("%d messages elided from memory", len(event.Messages))
+	case callback.EventHistory:
+		return fmt.Sprintf("%d messages kept in memory", len(event.Messages))
+	case callback.EventBlackboard:
+		switch {
+		case event.Entry == nil && event.OldEntry != nil:
+			return fmt.Sprintf("blackboard %s deleted", event.OldEntry.Key)
+		case event.Entry != nil && event.Entry.Author != "":
+			return fmt.Sprintf("%s wrote blackboard %s, version %d",
+				event.Entry.Author, event.Entry.Key, event.Entry.Version)
+		case event.Entry != nil:
+			return fmt.Sprintf("blackboard %s written, version %d", event.Entry.Key, event.Entry.Version)
+		}
+	case callback.EventError:
+		return "error: " + event.Error
+	case callback.EventPoll:
+		switch {
+		case event.Poll == nil:
+		case event.Poll.Decided():
+			return fmt.Sprintf("poll of %s decided %s", event.Poll.Coordinator, event.Poll.Winner)
+		default:
+			return fmt.Sprintf("poll of %s undecided", event.Poll.Coordinator)
+		}
+	}
+	return string(event.Type)
+}
+
+// Body is the text of event, e.g. the content of a message or the input
+// and output of a tool.
+func Body(event callback.Event) string {
+	switch event.Type {
+	case callback.EventMessageIn, callback.EventMessageOut:
+		if event.Message == nil {
+			return ""
+		}
+		return event.Message.Content
+	case callback.EventAgentEnd, callback.EventMemoryElided, callback.EventHistory:
+		lines := make([]string, 0, len(event.Messages))
+		for _, msg := range event.Messages {
+			lines = append(lines, fmt.Sprintf("%s -> %s: %s", msg.Sender, receiver(&msg), msg.Content))
+		}
+		return strings.Join(lines, "\n")
+	case callback.EventActionStart, callback.EventActionEnd:
+		if event.Action == nil {
+			return ""
+		}
+		body := fmt.Sprintf("Thought: %s\nInput: %s", event.Action.Thought, event.Action.Input)
+		if event.Type == callback.EventActionEnd {
+			body += "\nObservation: " + event.Action.Observation
+			if event.Action.Feedback != "" {
+				body += "\nFeedback: " + event.Action.Feedback
+			}
+		}
+		return body
+	case callback.EventLLMStart:
+		return event.Prompt
+	case callback.EventLLMEnd:
+		if event.Reasoning != "" {
+			return event.Reasoning + "\n\n" + event.Output
+		}
+		return event.Output
+	case callback.EventSOP:
+		return event.SOP
+	case callback.EventRetrieverEnd:
+		docs := make([]string, 0, len(event.Documents))
+		for _, doc := range event.Documents {
+			docs = append(docs, fmt.Sprintf("(%.3f) %s", doc.Score, doc.PageContent))
+		}
+		return strings.Join(docs, "\n")
+	case callback.EventFeedback:
+		return event.Feedback
+	case callback.EventBlackboard:
+		if event.Entry == nil {
+			return ""
+		}
+		return string(event.Entry.Value)
+	case callback.EventPoll:
+		if event.Poll == nil {
+			return ""
+		}
+		return schema.ConvertPollResult(event.Poll)
+	}
+	return ""
+}
+
+func receiver(msg *schema.Message) string {
+	if msg.Receiver == "" {
+		return "nobody"
+	}
+	return msg.Receiver
+}
@@ -0,0 +1,82 @@
+package transcript
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/antgroup/aievo/callback"
+	"github.com/antgroup/aievo/llm"
+	"github.com/antgroup/aievo/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func record(t *testing.T) []callback.Event {
+	var log bytes.Buffer
+	r := callback.NewRecorder(&log)
+	ctx := context.Background()
+	msg := &schema.Message{Type: schema.MsgTypeMsg, Sender: "User", Receiver: "writer", Content: "write <b>it</b>"}
+	r.HandleMessageInQueue(ctx, msg)
+	r.HandleMessageOutQueue(ctx, msg)
+	r.HandleLLMStart(ctx, "you are a writer")
+	r.HandleLLMEnd(ctx, &llm.Generation{Content: "```go\nfmt.Println()\n```", Usage: &llm.Usage{TotalTokens: 12}})
+	r.HandleFeedback(ctx, "writer", "NotApproved", "too short")
+	r.HandleAgentActionEnd(ctx, "writer", &schema.StepAction{Action: "search", Input: "go", Observation: "golang"})
+	r.HandleMessageInQueue(ctx, &schema.Message{Type: schema.MsgTypeEnd, Sender: "writer", Content: "done"})
+	require.NoError(t, r.Err())
+
+	events, err := Load(&log)
+	require.NoError(t, err)
+	require.Len(t, events, 7)
+	return events
+}
+
+func TestLoad(t *testing.T) {
+	events := record(t)
+	assert.Equal(t, 1, events[0].Seq)
+	assert.Equal(t, "write <b>it</b>", events[0].Message.Content)
+	assert.Equal(t, 12, events[3].Tokens)
+	assert.Equal(t, "writer got the result of search", Title(events[5]))
+	assert.Equal(t, "writer sent the final answer to nobody", Title(events[6]))
+
+	messages := Messages(events)
+	require.Len(t, messages, 2)
+	assert.True(t, messages[1].IsEnd())
+
+	// the messages kept in memory win over the queued ones
+	kept := []schema.Message{*events[0].Message}
+	events = append(events, callback.Event{Type: callback.EventHistory, Messages: kept})
+	assert.Equal(t, kept, Messages(events))
+	assert.Equal(t, "1 messages kept in memory", Title(events[7]))
+
+	_, err := Load(strings.NewReader("{}\nnot json\n"))
+	assert.EqualError(t, err, "line 2: invalid character 'o' in literal null (expecting 'u')")
+}
+
+func TestRender(t *testing.T) {
+	events := record(t)
+	var md, page bytes.Buffer
+	require.NoError(t, Markdown(&md, events))
+	assert.Contains(t, md.String(), "**User sent a message to writer**\n\n> write <b>it</b>")
+	assert.Contains(t, md.String(), "````\n```go\nfmt.Println()\n```\n````")
+	assert.NotContains(t, md.String(), "delivered")
+
+	require.NoError(t, HTML(&page, events))
+	assert.Contains(t, page.String(), "write &lt;b&gt;it&lt;/b&gt;")
+	assert.Contains(t, page.String(), `<div class="event feedback">`)
+}
+
+func TestReplay(t *testing.T) {
+	events := record(t)
+	var out bytes.Buffer
+	require.NoError(t, Replay(strings.NewReader("s\nb\nq\n"), &out, events))
+	assert.Equal(t, 3, strings.Count(out.String(), "\n#"))
+	assert.Contains(t, out.String(), "#7 ")
+	assert.Contains(t, out.String(), "#6 ")
+
+	out.Reset()
+	require.NoError(t, Replay(strings.NewReader("\nc\n"), &out, events))
+	assert.Equal(t, 7, strings.Count(out.String(), "\n#"))
+}
@@ -0,0 +1,347 @@
+package json
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var fences = []*regexp.Regexp{
+	regexp.MustCompile("(?s)```[\\w-]*[ \\t]*\\n?(.*?)```"),
+	regexp.MustCompile("(?s)~~~[\\w-]*[ \\t]*\\n?(.*?)~~~"),
+}
+
+// RepairJsonString extracts the JSON object or array from an LLM output and
+// repairs the common syntax errors of it, e.g. trailing commas, single
+// quotes, unescaped quotes and newlines in strings and unclosed brackets.
+// When there are several candidates, the valid one with the most keys wins.
+// Ties go to the longest one.
+// The trimmed input is returned if no candidate is found.
+func RepairJsonString(input string) string {
+	input = strings.TrimSpace(input)
+	if isContainer(input) && json.Valid([]byte(input)) {
+		return input
+	}
+	best, bestScore := "", -1
+	for _, candidate := range ExtractJsonCandidates(input) {
+		fixed := strings.TrimSpace(FixJsonString(candidate))
+		score := 0
+		if keys, ok := countKeys(fixed); ok {
+			score = 1 + keys
+		}
+		// on a tie, the longer one keeps more of the output
+		if score > bestScore || score == bestScore && len(fixed) > len(best) {
+			best, bestScore = fixed, score
+		}
+	}
+	if best == "" {
+		return TrimJsonString(input)
+	}
+	return best
+}
+
+// ExtractJsonCandidates returns the top level balanced objects and arrays
+// found in the fenced blocks of input and in input itself, in this order.
+// A candidate left unclosed at the end of its text runs to the end.
+func ExtractJsonCandidates(input string) []string {
+	sources := make([]string, 0)
+	for _, fence := range fences {
+		for _, match := range fence.FindAllStringSubmatch(input, -1) {
+			sources = append(sources, match[1])
+		}
+	}
+	sources = append(sources, input)
+
+	seen := make(map[string]bool)
+	candidates := make([]string, 0)
+	for _, source := range sources {
+		for _, candidate := range scanBalanced(source) {
+			if !seen[candidate] {
+				seen[candidate] = true
+				candidates = append(candidates, candidate)
+			}
+		}
+	}
+	return candidates
+}
+
+func scanBalanced(s string) []string {
+	candidates := make([]string, 0)
+	for i := 0; i < len(s); i++ {
+		if s[i] != '{' && s[i] != '[' {
+			continue
+		}
+		end := matchBracket(s, i)
+		candidates = append(candidates, strings.TrimSpace(s[i:end]))
+		i = end - 1
+	}
+	return candidates
+}
+
+// matchBracket returns the end of the container starting at start, or the
+// end of s when it is not closed.
+func matchBracket(s string, start int) int {
+	depth := 0
+	var quote byte
+	for i := start; i < len(s); i++ {
+		c := s[i]
+		if quote != 0 {
+			switch {
+			case c == '\\':
+				i++
+			case c == quote && closesString(s, i+1):
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '"', '\'':
+			quote = c
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(s)
+}
+
+// FixJsonString repairs the syntax errors of a JSON text in a single pass.
+func FixJsonString(s string) string {
+	out := make([]byte, 0, len(s)+16)
+	stack := make([]byte, 0)
+	// afterValue is set when a value or a key was just written, so that a
+	// missing comma can be inserted before the next one
+	afterValue := false
+	separate := func() {
+		if afterValue && len(stack) > 0 {
+			out = append(out, ',')
+		}
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\'':
+			separate()
+			out, i = appendString(out, s, i)
+			afterValue = true
+		case c == '{' || c == '[':
+			separate()
+			stack = append(stack, c)
+			out = append(out, c)
+			afterValue = false
+		case c == '}' || c == ']':
+			open := byte('{')
+			if c == ']' {
+				open = '['
+			}
+			if strings.IndexByte(string(stack), open) < 0 {
+				// stray closing bracket
+				continue
+			}
+			for len(stack) > 0 {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				out = append(trimComma(out), closer(top))
+				if top == open {
+					break
+				}
+			}
+			afterValue = true
+		case c == ',':
+			if last := lastByte(out); last != ',' && last != '{' && last != '[' && last != 0 {
+				out = append(out, c)
+			}
+			afterValue = false
+		case c == ':':
+			out = append(out, c)
+			afterValue = false
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			out = append(out, c)
+		case isWordByte(c):
+			j := i
+			for j < len(s) && isWordByte(s[j]) {
+				j++
+			}
+			separate()
+			out = append(out, literal(s[i:j], nextByte(s, j) == ':')...)
+			afterValue = true
+			i = j - 1
+		default:
+			out = append(out, c)
+		}
+	}
+	out = trimComma(out)
+	for i := len(stack) - 1; i >= 0; i-- {
+		out = append(out, closer(stack[i]))
+	}
+	return string(out)
+}
+
+// appendString writes the string starting at s[start] as a valid double
+// quoted JSON string and returns the index of its closing quote.
+func appendString(out []byte, s string, start int) ([]byte, int) {
+	quote := s[start]
+	out = append(out, '"')
+	for i := start + 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			if i+1 >= len(s) {
+				out = append(out, '\\', '\\')
+				continue
+			}
+			next := s[i+1]
+			switch {
+			case next == '\'':
+				out = append(out, '\'')
+			case next == 'u' && isHex(s[i+2:min(i+6, len(s))], 4):
+				out = append(out, '\\', 'u')
+			case strings.IndexByte(`"\/bfnrt`, next) >= 0:
+				out = append(out, '\\', next)
+			default:
+				// invalid escape, keep the backslash as it is
+				out = append(out, '\\', '\\')
+				continue
+			}
+			i++
+		case c == quote && closesString(s, i+1):
+			return append(out, '"'), i
+		case c == '"':
+			out = append(out, '\\', '"')
+		case c == '\n':
+			out = append(out, '\\', 'n')
+		case c == '\r':
+			out = append(out, '\\', 'r')
+		case c == '\t':
+			out = append(out, '\\', 't')
+		case c < 0x20:
+			out = append(out, fmt.Sprintf("\\u%04x", c)...)
+		default:
+			out = append(out, c)
+		}
+	}
+	return append(out, '"'), len(s)
+}
+
+// closesString reports whether a quote followed by s[i:] ends a string. A
+// quote followed by anything else is taken as part of the string.
+func closesString(s string, i int) bool {
+	newline := false
+	for ; i < len(s); i++ {
+		switch s[i] {
+		case ' ', '\t', '\r':
+		case '\n':
+			newline = true
+		case ',', ':', '}', ']':
+			return true
+		default:
+			return newline
+		}
+	}
+	return true
+}
+
+func literal(word string, isKey bool) string {
+	switch word {
+	case "True":
+		word = "true"
+	case "False":
+		word = "false"
+	case "None":
+		word = "null"
+	}
+	if isKey {
+		return `"` + word + `"`
+	}
+	return word
+}
+
+func closer(open byte) byte {
+	if open == '{' {
+		return '}'
+	}
+	return ']'
+}
+
+func trimComma(out []byte) []byte {
+	i := len(out) - 1
+	for i >= 0 && isSpace(out[i]) {
+		i--
+	}
+	if i >= 0 && out[i] == ',' {
+		return append(out[:i], out[i+1:]...)
+	}
+	return out
+}
+
+func lastByte(out []byte) byte {
+	for i := len(out) - 1; i >= 0; i-- {
+		if !isSpace(out[i]) {
+			return out[i]
+		}
+	}
+	return 0
+}
+
+func nextByte(s string, i int) byte {
+	for ; i < len(s); i++ {
+		if !isSpace(s[i]) {
+			return s[i]
+		}
+	}
+	return 0
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '_' || c == '.' || c == '+' || c == '-'
+}
+
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+func isContainer(s string) bool {
+	return strings.HasPrefix(s, "{") || strings.HasPrefix(s, "[")
+}
+
+// countKeys returns the number of keys of a valid object, or the number of
+// keys of the objects in a valid array.
+func countKeys(s string) (int, bool) {
+	var v any
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return 0, false
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		return len(v), true
+	case []any:
+		keys := 0
+		for _, e := range v {
+			if m, ok := e.(map[string]any); ok {
+				keys += len(m)
+			}
+		}
+		return keys, true
+	}
+	return 0, false
+}
@@ -0,0 +1,147 @@
+package json
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// _badOutputs are outputs of models that failed to parse, together with the
+// message expected to be parsed from them.
+var _badOutputs = []struct {
+	name   string
+	output string
+	want   map[string]any
+}{
+	{
+		name: "trailing comma",
+		output: `{
+  "thought": "I should ask the writer",
+  "cate": "MSG",
+  "receiver": "Writer",
+  "content": "write the report",
+}`,
+		want: map[string]any{"cate": "MSG", "receiver": "Writer", "content": "write the report"},
+	},
+	{
+		name:   "single quotes",
+		output: `{'cate': 'END', 'receiver': 'User', 'content': 'it\'s done'}`,
+		want:   map[string]any{"cate": "END", "content": "it's done"},
+	},
+	{
+		name:   "raw newlines in content",
+		output: "{\n\"cate\": \"MSG\",\n\"receiver\": \"Coder\",\n\"content\": \"please fix:\n\t- the parser\n\t- the tests\"\n}",
+		want:   map[string]any{"content": "please fix:\n\t- the parser\n\t- the tests"},
+	},
+	{
+		name: "prose around",
+		output: `Sure! Here's what I'll send, since the user's task {is} clear:
+{"cate": "MSG", "receiver": "Reviewer", "content": "review {this} please"}
+Let me know if anything else is needed.`,
+		want: map[string]any{"receiver": "Reviewer", "content": "review {this} please"},
+	},
+	{
+		name: "multiple objects",
+		output: `{"thought": "first I think"}
+{"thought": "then I act", "action": "calculator", "input": {"param": "20 * 30"}}`,
+		want: map[string]any{"action": "calculator", "input": map[string]any{"param": "20 * 30"}},
+	},
+	{
+		name:   "tilde fence",
+		output: "~~~json\n{\"cate\": \"END\", \"content\": \"42\"}\n~~~",
+		want:   map[string]any{"cate": "END", "content": "42"},
+	},
+	{
+		name:   "fence without language",
+		output: "Output:\n```\n{\"cate\": \"END\", \"content\": \"done\",}\n```",
+		want:   map[string]any{"cate": "END", "content": "done"},
+	},
+	{
+		name:   "unclosed fence and object",
+		output: "```json\n{\"cate\": \"MSG\", \"receiver\": \"Tester\", \"content\": \"run the tests",
+		want:   map[string]any{"receiver": "Tester", "content": "run the tests"},
+	},
+	{
+		name:   "unescaped quotes",
+		output: `{"cate": "MSG", "receiver": "Judge", "content": "he said "I am a civilian" twice"}`,
+		want:   map[string]any{"content": `he said "I am a civilian" twice`},
+	},
+	{
+		name: "missing commas",
+		output: `{
+  "cate": "MSG"
+  "receiver": "Judge"
+  "content": "vote"
+}`,
+		want: map[string]any{"cate": "MSG", "receiver": "Judge", "content": "vote"},
+	},
+	{
+		name:   "python literals and bare keys",
+		output: `{remove: ["Player1"], finished: True, reason: None}`,
+		want:   map[string]any{"remove": []any{"Player1"}, "finished": true, "reason": nil},
+	},
+	{
+		name:   "code in content",
+		output: "```json\n{\"cate\": \"END\", \"content\": \"```go\\nfunc main() {}\\n```\"}\n```",
+		want:   map[string]any{"cate": "END", "content": "```go\nfunc main() {}\n```"},
+	},
+}
+
+func TestRepairJsonString(t *testing.T) {
+	for _, c := range _badOutputs {
+		t.Run(c.name, func(t *testing.T) {
+			repaired := RepairJsonString(c.output)
+			got := make(map[string]any)
+			require.NoError(t, json.Unmarshal([]byte(repaired), &got), repaired)
+			for k, v := range c.want {
+				assert.Equal(t, v, got[k], k)
+			}
+		})
+	}
+}
+
+func TestRepairJsonStringArray(t *testing.T) {
+	repaired := RepairJsonString(`Steps:
+[
+  {"action": "search", "input": "golang",},
+  {"action": "read", "input": {"url": 'https://go.dev'}},
+]`)
+	var got []map[string]any
+	require.NoError(t, json.Unmarshal([]byte(repaired), &got), repaired)
+	assert.Len(t, got, 2)
+	assert.Equal(t, map[string]any{"url": "https://go.dev"}, got[1]["input"])
+}
+
+func TestRepairJsonStringKeep(t *testing.T) {
+	valid := `{"content": "a,\tb"}`
+	assert.Equal(t, valid, RepairJsonString("  "+valid+"\n"))
+	assert.Equal(t, "no json here", RepairJsonString("no json here"))
+	assert.Equal(t, "", RepairJsonString(""))
+}
+
+func FuzzRepairJsonString(f *testing.F) {
+	for _, c := range _badOutputs {
+		f.Add(c.output)
+	}
+	f.Add(`{"a": [1, 2, {"b": "c"}]}`)
+	f.Add(`[{"x": 'é\q'}`)
+	f.Fuzz(func(t *testing.T, input string) {
+		repaired := RepairJsonString(input)
+		trimmed := strings.TrimSpace(input)
+		if isContainer(trimmed) && json.Valid([]byte(trimmed)) {
+			if repaired != trimmed {
+				t.Fatalf("valid json %q changed to %q", trimmed, repaired)
+			}
+			return
+		}
+		// repairing a repaired json must not change it
+		if json.Valid([]byte(repaired)) && isContainer(repaired) {
+			if again := RepairJsonString(repaired); again != repaired {
+				t.Fatalf("repair is not stable: %q -> %q", repaired, again)
+			}
+		}
+	})
+}
@@ -3,6 +3,7 @@ package pdf
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"os/exec"
 	"reflect"
@@ -33,8 +34,8 @@ type PopplerTsvRow struct {
 }
 
 // CheckPopplerVersion checks if the installed version of poppler is compatible
-func CheckPopplerVersion() error {
-	cmd := exec.Command("pdftotext", "-v")
+func CheckPopplerVersion(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, "pdftotext", "-v")
 
 	var out bytes.Buffer
 	cmd.Stderr = &out
@@ -81,8 +82,8 @@ func CheckPopplerVersion() error {
 }
 
 // ExtractOrError Just like Extract, but indicates issues with errors
-func ExtractOrError(pdfBytes []byte) (pages []Page, err error) {
-	if pages, err = Extract(pdfBytes); err != nil {
+func ExtractOrError(ctx context.Context, pdfBytes []byte) (pages []Page, err error) {
+	if pages, err = Extract(ctx, pdfBytes); err != nil {
 		return pages, err
 	}
 
@@ -106,9 +107,9 @@ func ExtractOrError(pdfBytes []byte) (pages []Page, err error) {
 }
 
 // Extract PDF text content in simplified format
-func Extract(pdfBytes []byte) (pdfPages []Page, err error) {
+func Extract(ctx context.Context, pdfBytes []byte) (pdfPages []Page, err error) {
 	var tsv []PopplerTsvRow
-	if tsv, err = ExtractInPopplerTsv(pdfBytes); err != nil {
+	if tsv, err = ExtractInPopplerTsv(ctx, pdfBytes); err != nil {
 		return nil, err
 	}
 
@@ -138,14 +139,14 @@ func Extract(pdfBytes []byte) (pdfPages []Page, err error) {
 }
 
 // ExtractInPopplerTsv Access raw stdout content from Poppler
-func ExtractInPopplerTsv(pdfBytes []byte) (tsvRows []PopplerTsvRow, err error) {
+func ExtractInPopplerTsv(ctx context.Context, pdfBytes []byte) (tsvRows []PopplerTsvRow, err error) {
 	params := []string{
 		"-tsv",
 		"-", // Read from stdin
 		"-", // Write to stdout
 	}
 
-	cmd := exec.Command("pdftotext", params...)
+	cmd := exec.CommandContext(ctx, "pdftotext", params...)
 	cmd.Stdin = bytes.NewReader(pdfBytes)
 
 	var out bytes.Buffer
@@ -0,0 +1,20 @@
+package utils
+
+import (
+	"unicode/utf8"
+)
+
+// EstimateTokens roughly estimates the number of tokens of s without a
+// tokenizer: about four ascii characters per token, and one token per
+// character for the others, e.g. chinese.
+func EstimateTokens(s string) int {
+	ascii, other := 0, 0
+	for _, r := range s {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
@@ -0,0 +1,187 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/antgroup/aievo/schema"
+	"github.com/antgroup/aievo/tool"
+	ujson "github.com/antgroup/aievo/utils/json"
+)
+
+const _defaultSender = "User"
+
+// Node is a step of a workflow. It receives the payload of the edge that
+// activated it, or for a join node a map of the payloads keyed by the name
+// of the node they come from, and returns the payload of its out edges.
+type Node interface {
+	Name() string
+	Run(ctx context.Context, in any, state *State) (any, error)
+}
+
+type funcNode struct {
+	name string
+	fn   func(ctx context.Context, in any, state *State) (any, error)
+}
+
+// Func makes a node of a plain function. The payload is converted to I:
+// a string is decoded as json when I is not a string, and other payloads
+// are converted via json.
+func Func[I, O any](name string, fn func(ctx context.Context, in I, state *State) (O, error)) Node {
+	return &funcNode{
+		name: name,
+		fn: func(ctx context.Context, in any, state *State) (any, error) {
+			v, err := Convert[I](in)
+			if err != nil {
+				return nil, err
+			}
+			return fn(ctx, v, state)
+		},
+	}
+}
+
+func (n *funcNode) Name() string {
+	return n.name
+}
+
+func (n *funcNode) Run(ctx context.Context, in any, state *State) (any, error) {
+	return n.fn(ctx, in, state)
+}
+
+type agentNode struct {
+	agent schema.Agent
+}
+
+// AgentNode makes a node of an agent. The payload is sent to the agent as
+// a message from the user, and the content of its first message is the
+// payload of the out edges. A schema.Message or []schema.Message payload
+// is passed as is.
+func AgentNode(agent schema.Agent) Node {
+	return &agentNode{agent: agent}
+}
+
+func (n *agentNode) Name() string {
+	return n.agent.Name()
+}
+
+func (n *agentNode) Run(ctx context.Context, in any, _ *State) (any, error) {
+	gen, err := n.agent.Run(ctx, n.messages(in))
+	if err != nil {
+		return nil, err
+	}
+	return n.output(gen)
+}
+
+func (n *agentNode) messages(in any) []schema.Message {
+	switch v := in.(type) {
+	case []schema.Message:
+		return v
+	case schema.Message:
+		return []schema.Message{v}
+	}
+	return []schema.Message{{
+		Type:     schema.MsgTypeMsg,
+		Content:  Text(in),
+		Sender:   _defaultSender,
+		Receiver: n.agent.Name(),
+	}}
+}
+
+func (n *agentNode) output(gen *schema.Generation) (any, error) {
+	if gen == nil || len(gen.Messages) == 0 {
+		return nil, fmt.Errorf("generating messages is nil for agent %s", n.agent.Name())
+	}
+	return gen.Messages[0].Content, nil
+}
+
+type toolNode struct {
+	tool tool.Tool
+}
+
+// ToolNode makes a node of a tool, the payload is the input of the tool
+// and its observation is the payload of the out edges.
+func ToolNode(t tool.Tool) Node {
+	return &toolNode{tool: t}
+}
+
+func (n *toolNode) Name() string {
+	return n.tool.Name()
+}
+
+func (n *toolNode) Run(ctx context.Context, in any, _ *State) (any, error) {
+	return n.tool.Call(ctx, Text(in))
+}
+
+// Convert converts a payload to T, see Func.
+func Convert[T any](in any) (T, error) {
+	var v T
+	if in == nil {
+		return v, nil
+	}
+	if t, ok := in.(T); ok {
+		return t, nil
+	}
+	var data []byte
+	if s, ok := in.(string); ok {
+		data = []byte(ujson.RepairJsonString(s))
+	} else {
+		var err error
+		if data, err = json.Marshal(in); err != nil {
+			return v, fmt.Errorf("%w: %w", ErrPayloadType, err)
+		}
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return v, fmt.Errorf("%w: cannot convert %T to %s: %w",
+			ErrPayloadType, in, reflect.TypeFor[T](), err)
+	}
+	return v, nil
+}
+
+// Text renders a payload for agents and tools. The payload of a join node
+// is rendered as one line per node it comes from.
+func Text(in any) string {
+	switch v := in.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	case map[string]any:
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var sb strings.Builder
+		for _, name := range names {
+			sb.WriteString(fmt.Sprintf("%s: %s\n", name, Text(v[name])))
+		}
+		return sb.String()
+	}
+	bytes, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Sprint(in)
+	}
+	return string(bytes)
+}
+
+// runAgent is like Run, with callbacks and generate options.
+func (n *agentNode) runAgent(ctx context.Context, in any, r *run) (any, error) {
+	messages := n.messages(in)
+	if r.callback != nil {
+		r.callback.HandleAgentStart(ctx, n.agent, messages)
+	}
+	gen, err := n.agent.Run(ctx, messages, r.llmOptions...)
+	if err != nil {
+		return nil, err
+	}
+	if r.callback != nil {
+		r.callback.HandleAgentEnd(ctx, n.agent, gen)
+	}
+	return n.output(gen)
+}
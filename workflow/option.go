@@ -0,0 +1,109 @@
+package workflow
+
+import (
+	"github.com/antgroup/aievo/callback"
+	"github.com/antgroup/aievo/llm"
+)
+
+type Option func(*Workflow)
+
+// WithName sets the name the workflow reports the runs of its nodes with.
+func WithName(name string) Option {
+	return func(w *Workflow) {
+		w.name = name
+	}
+}
+
+func WithCallback(handler callback.Handler) Option {
+	return func(w *Workflow) {
+		w.callback = handler
+	}
+}
+
+// WithMaxIterations sets how many times each node may run in a run, so
+// loops end. It defaults to 10, and can be set per node by
+// WithNodeMaxIterations.
+func WithMaxIterations(n int) Option {
+	return func(w *Workflow) {
+		w.maxIterations = n
+	}
+}
+
+// WithMaxParallel limits the number of nodes running at once, there is no
+// limit by default.
+func WithMaxParallel(n int) Option {
+	return func(w *Workflow) {
+		w.maxParallel = n
+	}
+}
+
+type NodeOption func(*node)
+
+// WithJoin makes the node wait for all the branches running to finish, it
+// is then run once with the payloads of the edges taken to it, keyed by
+// the name of the node they come from.
+func WithJoin() NodeOption {
+	return func(n *node) {
+		n.join = true
+	}
+}
+
+func WithNodeMaxIterations(max int) NodeOption {
+	return func(n *node) {
+		n.maxIterations = max
+	}
+}
+
+type EdgeOption func(*edge)
+
+// WithCondition makes the edge taken only when condition holds.
+func WithCondition(condition Condition) EdgeOption {
+	return func(e *edge) {
+		e.condition = condition
+	}
+}
+
+// When is like WithCondition for a payload of type T, the edge is not
+// taken when the payload cannot be converted to T.
+func When[T any](condition func(payload T, state *State) bool) EdgeOption {
+	return WithCondition(func(payload any, state *State) bool {
+		v, err := Convert[T](payload)
+		if err != nil {
+			return false
+		}
+		return condition(v, state)
+	})
+}
+
+// WithFallback makes the edge taken only when no other edge of its source
+// is taken.
+func WithFallback() EdgeOption {
+	return func(e *edge) {
+		e.fallback = true
+	}
+}
+
+type RunOption func(*run)
+
+// WithState runs the workflow with state instead of an empty one, so the
+// caller can pass values in and read them after the run.
+func WithState(state *State) RunOption {
+	return func(r *run) {
+		r.state = state
+	}
+}
+
+// WithRunCallback sets the callback of the run instead of the one of the
+// workflow.
+func WithRunCallback(handler callback.Handler) RunOption {
+	return func(r *run) {
+		r.callback = handler
+	}
+}
+
+// WithGenerateOptions sets the options the agent nodes are run with.
+func WithGenerateOptions(opts ...llm.GenerateOption) RunOption {
+	return func(r *run) {
+		r.llmOptions = opts
+	}
+}
@@ -0,0 +1,48 @@
+package workflow
+
+import (
+	"sync"
+)
+
+// State is shared by all the nodes of a run, it is safe for concurrent use
+// by parallel branches.
+type State struct {
+	values map[string]any
+	mu     sync.RWMutex
+}
+
+func NewState() *State {
+	return &State{values: make(map[string]any)}
+}
+
+func (s *State) Get(key string) (any, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.values[key]
+	return v, ok
+}
+
+func (s *State) Set(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+}
+
+// Update sets key to the result of fn applied to its current value, as
+// one atomic step.
+func (s *State) Update(key string, fn func(old any) any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = fn(s.values[key])
+}
+
+// Values returns a copy of all the values.
+func (s *State) Values() map[string]any {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	values := make(map[string]any, len(s.values))
+	for k, v := range s.values {
+		values[k] = v
+	}
+	return values
+}
@@ -0,0 +1,295 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/antgroup/aievo/callback"
+	"github.com/antgroup/aievo/llm"
+	"github.com/antgroup/aievo/schema"
+)
+
+var (
+	ErrMissingNode    = errors.New("node is not found")
+	ErrDuplicateNode  = errors.New("node already exists")
+	ErrMissingStart   = errors.New("start node is not set")
+	ErrMaxIterations  = errors.New("node reached max iterations")
+	ErrNotFinished    = errors.New("end node is not reached")
+	ErrPayloadType    = errors.New("unexpected payload type")
+	ErrInvalidOptions = errors.New("invalid options")
+)
+
+const (
+	_defaultName          = "workflow"
+	_defaultMaxIterations = 10
+)
+
+// Condition decides whether an edge is taken for the payload of its source.
+type Condition func(payload any, state *State) bool
+
+type node struct {
+	Node
+	join          bool
+	maxIterations int
+	order         int
+}
+
+type edge struct {
+	to        string
+	condition Condition
+	fallback  bool
+}
+
+// Workflow runs nodes along fixed edges instead of letting the LLM choose
+// the receivers. A node is run once for each edge taken to it, the edges
+// taken from one node run in parallel, and join nodes wait for all the
+// branches running to finish before being run with their payloads.
+type Workflow struct {
+	name          string
+	callback      callback.Handler
+	maxIterations int
+	maxParallel   int
+
+	nodes map[string]*node
+	edges map[string][]edge
+	start string
+	end   string
+}
+
+func New(opts ...Option) *Workflow {
+	w := &Workflow{
+		name:          _defaultName,
+		maxIterations: _defaultMaxIterations,
+		nodes:         make(map[string]*node),
+		edges:         make(map[string][]edge),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// AddNode adds n to the workflow, the first node added is the start node
+// unless SetStart is called.
+func (w *Workflow) AddNode(n Node, opts ...NodeOption) error {
+	if n == nil || n.Name() == "" {
+		return fmt.Errorf("%w: node without name", ErrInvalidOptions)
+	}
+	if _, ok := w.nodes[n.Name()]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateNode, n.Name())
+	}
+	nd := &node{
+		Node:          n,
+		maxIterations: w.maxIterations,
+		order:         len(w.nodes),
+	}
+	for _, opt := range opts {
+		opt(nd)
+	}
+	w.nodes[n.Name()] = nd
+	if w.start == "" {
+		w.start = n.Name()
+	}
+	return nil
+}
+
+// AddEdge adds an edge from the node from to the node to. Edges are taken
+// in the order they are added.
+func (w *Workflow) AddEdge(from, to string, opts ...EdgeOption) error {
+	if _, ok := w.nodes[from]; !ok {
+		return fmt.Errorf("%w: %s", ErrMissingNode, from)
+	}
+	if _, ok := w.nodes[to]; !ok {
+		return fmt.Errorf("%w: %s", ErrMissingNode, to)
+	}
+	e := edge{to: to}
+	for _, opt := range opts {
+		opt(&e)
+	}
+	w.edges[from] = append(w.edges[from], e)
+	return nil
+}
+
+func (w *Workflow) SetStart(name string) error {
+	if _, ok := w.nodes[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrMissingNode, name)
+	}
+	w.start = name
+	return nil
+}
+
+// SetEnd sets the node whose payload is the result of the workflow. When
+// it is not set, the result is the payload of the last node run.
+func (w *Workflow) SetEnd(name string) error {
+	if _, ok := w.nodes[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrMissingNode, name)
+	}
+	w.end = name
+	return nil
+}
+
+type activation struct {
+	node *node
+	in   any
+}
+
+type result struct {
+	node *node
+	out  any
+	err  error
+}
+
+type run struct {
+	callback   callback.Handler
+	llmOptions []llm.GenerateOption
+	state      *State
+}
+
+// Run runs the workflow from the start node with input as its payload.
+func (w *Workflow) Run(ctx context.Context, input any, opts ...RunOption) (any, error) {
+	if w.start == "" {
+		return nil, ErrMissingStart
+	}
+	r := &run{callback: w.callback}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.state == nil {
+		r.state = NewState()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	queue := []activation{{node: w.nodes[w.start], in: input}}
+	joins := make(map[string]map[string]any)
+	iterations := make(map[string]int)
+	results := make(chan result)
+	running := 0
+	var (
+		last  any
+		end   any
+		ended bool
+		err   error
+	)
+	for {
+		for err == nil && len(queue) > 0 &&
+			(w.maxParallel <= 0 || running < w.maxParallel) {
+			a := queue[0]
+			queue = queue[1:]
+			iterations[a.node.Name()]++
+			if a.node.maxIterations > 0 && iterations[a.node.Name()] > a.node.maxIterations {
+				err = fmt.Errorf("%w: %s", ErrMaxIterations, a.node.Name())
+				break
+			}
+			running++
+			go func() {
+				out, err := w.runNode(ctx, a.node, a.in, r)
+				results <- result{node: a.node, out: out, err: err}
+			}()
+		}
+		if running == 0 {
+			if err != nil {
+				return nil, err
+			}
+			if join := w.readyJoin(joins); join != nil {
+				queue = append(queue, activation{node: join, in: joins[join.Name()]})
+				delete(joins, join.Name())
+				continue
+			}
+			break
+		}
+
+		res := <-results
+		running--
+		if err != nil {
+			continue
+		}
+		if res.err != nil {
+			// wait for the other branches before returning
+			err = fmt.Errorf("node %s: %w", res.node.Name(), res.err)
+			cancel()
+			continue
+		}
+		last = res.out
+		if res.node.Name() == w.end {
+			end, ended = res.out, true
+		}
+		for _, to := range w.route(res.node.Name(), res.out, r.state) {
+			if to.join {
+				if joins[to.Name()] == nil {
+					joins[to.Name()] = make(map[string]any)
+				}
+				joins[to.Name()][res.node.Name()] = res.out
+				continue
+			}
+			queue = append(queue, activation{node: to, in: res.out})
+		}
+	}
+
+	if w.end == "" {
+		return last, nil
+	}
+	if !ended {
+		return nil, ErrNotFinished
+	}
+	return end, nil
+}
+
+// route returns the targets of the edges taken from the node from.
+func (w *Workflow) route(from string, out any, state *State) []*node {
+	targets := make([]*node, 0)
+	fallbacks := make([]*node, 0)
+	for _, e := range w.edges[from] {
+		if e.fallback {
+			fallbacks = append(fallbacks, w.nodes[e.to])
+			continue
+		}
+		if e.condition == nil || e.condition(out, state) {
+			targets = append(targets, w.nodes[e.to])
+		}
+	}
+	if len(targets) == 0 {
+		return fallbacks
+	}
+	return targets
+}
+
+// readyJoin returns the first added join node having payloads.
+func (w *Workflow) readyJoin(joins map[string]map[string]any) *node {
+	var ready *node
+	for name := range joins {
+		n := w.nodes[name]
+		if ready == nil || n.order < ready.order {
+			ready = n
+		}
+	}
+	return ready
+}
+
+func (w *Workflow) runNode(ctx context.Context, n *node, in any, r *run) (any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if an, ok := n.Node.(*agentNode); ok {
+		return an.runAgent(ctx, in, r)
+	}
+	action := &schema.StepAction{
+		Action: n.Name(),
+		Input:  Text(in),
+	}
+	if r.callback != nil {
+		r.callback.HandleAgentActionStart(ctx, w.name, action)
+	}
+	out, err := n.Run(ctx, in, r.state)
+	if err != nil {
+		action.Feedback = err.Error()
+	} else {
+		action.Observation = Text(out)
+	}
+	if r.callback != nil {
+		r.callback.HandleAgentActionEnd(ctx, w.name, action)
+	}
+	return out, err
+}
@@ -0,0 +1,145 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/antgroup/aievo/llm"
+	"github.com/antgroup/aievo/schema"
+	"github.com/antgroup/aievo/tool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type upperAgent struct{}
+
+func (upperAgent) Run(_ context.Context, messages []schema.Message,
+	_ ...llm.GenerateOption) (*schema.Generation, error) {
+	return &schema.Generation{Messages: []schema.Message{{
+		Type:    schema.MsgTypeMsg,
+		Content: strings.ToUpper(messages[len(messages)-1].Content),
+		Sender:  "upper",
+	}}}, nil
+}
+
+func (upperAgent) Name() string                 { return "upper" }
+func (upperAgent) Description() string          { return "upper" }
+func (upperAgent) WithEnv(_ schema.Environment) {}
+func (upperAgent) Env() schema.Environment      { return nil }
+func (upperAgent) Tools() []tool.Tool           { return nil }
+
+type reverseTool struct{}
+
+func (reverseTool) Name() string                   { return "reverse" }
+func (reverseTool) Description() string            { return "reverse" }
+func (reverseTool) Schema() *tool.PropertiesSchema { return nil }
+func (reverseTool) Strict() bool                   { return false }
+func (reverseTool) Call(_ context.Context, input string) (string, error) {
+	runes := []rune(input)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes), nil
+}
+
+type review struct {
+	Score int    `json:"score"`
+	Text  string `json:"text"`
+}
+
+func TestPipeline(t *testing.T) {
+	w := New()
+	require.NoError(t, w.AddNode(AgentNode(upperAgent{})))
+	require.NoError(t, w.AddNode(ToolNode(reverseTool{})))
+	require.NoError(t, w.AddNode(Func("wrap", func(_ context.Context, in string, state *State) (review, error) {
+		state.Set("seen", in)
+		return review{Score: len(in), Text: in}, nil
+	})))
+	require.NoError(t, w.AddEdge("upper", "reverse"))
+	require.NoError(t, w.AddEdge("reverse", "wrap"))
+
+	state := NewState()
+	out, err := w.Run(context.Background(), "abc", WithState(state))
+	require.NoError(t, err)
+	assert.Equal(t, review{Score: 3, Text: "CBA"}, out)
+	seen, _ := state.Get("seen")
+	assert.Equal(t, "CBA", seen)
+	assert.ErrorIs(t, w.AddEdge("wrap", "missing"), ErrMissingNode)
+	assert.ErrorIs(t, w.AddNode(ToolNode(reverseTool{})), ErrDuplicateNode)
+}
+
+func TestFanOutFanIn(t *testing.T) {
+	var running atomic.Int32
+	branch := func(name string) Node {
+		return Func(name, func(_ context.Context, in int, _ *State) (int, error) {
+			running.Add(1)
+			// every branch waits for the others, which only works when
+			// they run in parallel
+			for running.Load() < 3 {
+				time.Sleep(time.Millisecond)
+			}
+			return in * len(name), nil
+		})
+	}
+	w := New()
+	require.NoError(t, w.AddNode(Func("split", func(_ context.Context, in int, _ *State) (int, error) {
+		return in, nil
+	})))
+	require.NoError(t, w.AddNode(Func("sum", func(_ context.Context, in map[string]int, _ *State) (int, error) {
+		total := 0
+		for _, v := range in {
+			total += v
+		}
+		return total + len(in)*100, nil
+	}), WithJoin()))
+	for _, name := range []string{"a", "bb", "ccc"} {
+		require.NoError(t, w.AddNode(branch(name)))
+		require.NoError(t, w.AddEdge("split", name))
+		require.NoError(t, w.AddEdge(name, "sum"))
+	}
+	require.NoError(t, w.SetEnd("sum"))
+
+	out, err := w.Run(context.Background(), 2)
+	require.NoError(t, err)
+	assert.Equal(t, 312, out)
+}
+
+func TestLoop(t *testing.T) {
+	w := New()
+	require.NoError(t, w.AddNode(Func("write", func(_ context.Context, in review, _ *State) (review, error) {
+		in.Score++
+		in.Text += "!"
+		return in, nil
+	})))
+	require.NoError(t, w.AddNode(Func("publish", func(_ context.Context, in review, _ *State) (string, error) {
+		return in.Text, nil
+	})))
+	require.NoError(t, w.AddEdge("write", "write", When(func(r review, _ *State) bool {
+		return r.Score < 3
+	})))
+	require.NoError(t, w.AddEdge("write", "publish", WithFallback()))
+
+	out, err := w.Run(context.Background(), `{"score": 0, "text": "hi"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "hi!!!", out)
+
+	_, err = w.Run(context.Background(), review{Score: -20})
+	assert.ErrorIs(t, err, ErrMaxIterations)
+}
+
+func TestNodeError(t *testing.T) {
+	failed := errors.New("failed")
+	w := New()
+	require.NoError(t, w.AddNode(Func("fail", func(_ context.Context, _ any, _ *State) (any, error) {
+		return nil, failed
+	})))
+	_, err := w.Run(context.Background(), nil)
+	assert.ErrorIs(t, err, failed)
+
+	_, err = New().Run(context.Background(), nil)
+	assert.ErrorIs(t, err, ErrMissingStart)
+}